@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/torrentdata"
+)
+
+func runCreate(args []string) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	var announce, webSeeds stringList
+	fs.Var(&announce, "a", "tracker tier as comma-separated announce URLs (repeatable)")
+	fs.Var(&webSeeds, "w", "web seed URL (repeatable)")
+	output := fs.String("o", "", "output path (default: <name>.torrent)")
+	comment := fs.String("c", "", "comment")
+	createdBy := fs.String("created-by", "gotorrent", "creator name")
+	noDate := fs.Bool("no-date", false, "omit the creation date")
+	pieceLength := fs.Int("piece-length", 0, "piece length in bytes (default: automatic)")
+	private := fs.Bool("private", false, "mark the torrent as private")
+	source := fs.String("source", "", "source tag")
+	workers := fs.Int("workers", 0, "number of hashing workers (default: number of CPUs)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: gotorrent create [flags] <path>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected exactly one path")
+	}
+	root := fs.Arg(0)
+
+	opts := torrentdata.CreateOptions{
+		PieceLength: *pieceLength,
+		Comment:     *comment,
+		CreatedBy:   *createdBy,
+		Private:     *private,
+		Source:      *source,
+		WebSeeds:    webSeeds,
+		Workers:     *workers,
+	}
+	for _, tier := range announce {
+		opts.AnnounceList = append(opts.AnnounceList, strings.Split(tier, ","))
+	}
+	if !*noDate {
+		opts.CreationDate = time.Now()
+	}
+
+	metainfoFile, err := torrentdata.Create(root, opts)
+	if err != nil {
+		return err
+	}
+
+	path := *output
+	if path == "" {
+		path = metainfoFile.Info.Name + ".torrent"
+	}
+	if err := writeTorrentFile(path, metainfoFile); err != nil {
+		return err
+	}
+	fmt.Println(path)
+	return nil
+}
+
+// writeTorrentFile writes the metainfo file to path through a temporary file in the same
+// directory, so that an existing torrent is only replaced once the new one is complete.
+func writeTorrentFile(path string, metainfoFile *torrentdata.MetainfoFile) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	w := bufio.NewWriter(file)
+	if err := metainfoFile.Write(w); err != nil {
+		file.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Chmod(0o644); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
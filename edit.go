@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/mattheworford/gotorrent/internal/torrentdata"
+)
+
+func runEdit(args []string) error {
+	fs := flag.NewFlagSet("edit", flag.ContinueOnError)
+	var announce, replace, webSeeds stringList
+	fs.Var(&announce, "a", "replace the trackers; tier as comma-separated announce URLs (repeatable)")
+	fs.Var(&replace, "replace-tracker", "swap a tracker URL, as old=new (repeatable)")
+	clearTrackers := fs.Bool("clear-trackers", false, "remove all trackers")
+	fs.Var(&webSeeds, "w", "replace the web seeds; web seed URL (repeatable)")
+	clearWebSeeds := fs.Bool("clear-web-seeds", false, "remove all web seeds")
+	comment := fs.String("c", "", "set the comment (empty to remove)")
+	createdBy := fs.String("created-by", "", "set the creator name (empty to remove)")
+	output := fs.String("o", "", "output path (default: rewrite the input file)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: gotorrent edit [flags] <file.torrent>")
+		fmt.Fprintln(fs.Output(), "Only top-level keys are edited; the info dictionary and info hash are left unchanged.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected exactly one torrent file")
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	metainfoFile, err := torrentdata.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *clearTrackers {
+		metainfoFile.SetTrackers(nil)
+	}
+	if len(announce) > 0 {
+		var tiers [][]string
+		for _, tier := range announce {
+			tiers = append(tiers, strings.Split(tier, ","))
+		}
+		metainfoFile.SetTrackers(tiers)
+	}
+	for _, swap := range replace {
+		oldURL, newURL, ok := strings.Cut(swap, "=")
+		if !ok {
+			return fmt.Errorf("invalid -replace-tracker %q, expected old=new", swap)
+		}
+		if !metainfoFile.ReplaceTracker(oldURL, newURL) {
+			return fmt.Errorf("tracker %q not found", oldURL)
+		}
+	}
+	if *clearWebSeeds {
+		metainfoFile.URLList = nil
+	}
+	if len(webSeeds) > 0 {
+		metainfoFile.URLList = torrentdata.URLList(webSeeds)
+	}
+	if set["c"] {
+		metainfoFile.Comment = *comment
+	}
+	if set["created-by"] {
+		metainfoFile.CreatedBy = *createdBy
+	}
+
+	path := *output
+	if path == "" {
+		path = fs.Arg(0)
+	}
+	if err := writeTorrentFile(path, metainfoFile); err != nil {
+		return err
+	}
+	fmt.Println(path)
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mattheworford/gotorrent/internal/torrentdata"
+)
+
+func runInfo(args []string) error {
+	fs := flag.NewFlagSet("info", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the metadata as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: gotorrent info [flags] <file.torrent|url>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected exactly one torrent file or URL")
+	}
+
+	torrent, err := loadTorrent(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	summary := torrent.Summary()
+	if *asJSON {
+		return summary.WriteJSON(os.Stdout)
+	}
+	return summary.WriteText(os.Stdout)
+}
+
+// loadTorrent reads a torrent from a local path or an http(s) URL.
+func loadTorrent(source string) (*torrentdata.TorrentData, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return torrentdata.Fetch(context.Background(), source, torrentdata.FetchOptions{})
+	}
+	file, err := os.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return torrentdata.Parse(file)
+}
@@ -0,0 +1,133 @@
+// Package bencode implements the bencoding used by BitTorrent metainfo files and tracker
+// responses. The decoder bounds nesting depth, string length and total size so that it can
+// be used on untrusted input, and can optionally reject input that is not in canonical form.
+package bencode
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Limits bounds the resources a Decoder spends on a single value. Zero fields are unlimited.
+type Limits struct {
+	// MaxDepth is the maximum nesting of lists and dictionaries.
+	MaxDepth int
+	// MaxStringLength is the maximum length of a single string in bytes.
+	MaxStringLength int
+	// MaxSize is the maximum encoded size of a value in bytes.
+	MaxSize int64
+}
+
+// DefaultLimits are generous enough for any real-world torrent while keeping the memory
+// spent on hostile input bounded.
+var DefaultLimits = Limits{
+	MaxDepth:        256,
+	MaxStringLength: 32 << 20,
+	MaxSize:         64 << 20,
+}
+
+// ErrLimitExceeded is wrapped by the errors reporting input that exceeds a Decoder's Limits.
+var ErrLimitExceeded = errors.New("bencode: limit exceeded")
+
+// A SyntaxError describes input that is not valid bencode, or not canonical in strict mode.
+type SyntaxError struct {
+	Msg    string
+	Offset int64
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("bencode: %s at offset %d", e.Msg, e.Offset)
+}
+
+// An UnmarshalTypeError describes a bencoded value that cannot be stored in a Go value.
+type UnmarshalTypeError struct {
+	Value  string
+	Type   reflect.Type
+	Field  string
+	Offset int64
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("bencode: cannot unmarshal %s into field %q of type %s at offset %d", e.Value, e.Field, e.Type, e.Offset)
+	}
+	return fmt.Sprintf("bencode: cannot unmarshal %s into Go value of type %s at offset %d", e.Value, e.Type, e.Offset)
+}
+
+// An UnsupportedTypeError is returned by Marshal for values that have no bencoded form.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return "bencode: unsupported type " + e.Type.String()
+}
+
+// Marshaler is implemented by types that encode themselves as a single bencoded value.
+type Marshaler interface {
+	MarshalBencode() ([]byte, error)
+}
+
+// Unmarshaler is implemented by types that decode themselves from a single bencoded value.
+// The data passed to UnmarshalBencode is only valid for the duration of the call.
+type Unmarshaler interface {
+	UnmarshalBencode(data []byte) error
+}
+
+// RawMessage is a raw encoded bencode value. It preserves the exact bytes of a value, so
+// that decoding and re-encoding it is lossless.
+type RawMessage []byte
+
+// MarshalBencode returns m as the bencoding of m.
+func (m RawMessage) MarshalBencode() ([]byte, error) {
+	if len(m) == 0 {
+		return nil, errors.New("bencode: empty RawMessage")
+	}
+	return m, nil
+}
+
+// UnmarshalBencode sets *m to a copy of data.
+func (m *RawMessage) UnmarshalBencode(data []byte) error {
+	*m = append((*m)[:0], data...)
+	return nil
+}
+
+// field describes a struct field that is encoded as a dictionary entry.
+type field struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+// structFields returns the encoded fields of a struct type sorted by name. Fields are named
+// by their bencode tag, or by the Go field name when untagged; a tag of "-" skips the field.
+func structFields(t reflect.Type) []field {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]field)
+	}
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag := sf.Tag.Get("bencode")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, field{name: name, index: i, omitEmpty: options == "omitempty"})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].name < fields[j].name })
+	fieldCache.Store(t, fields)
+	return fields
+}
@@ -0,0 +1,506 @@
+package bencode
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// stringChunk bounds the allocation made ahead of reading a string, so that a huge declared
+// length on truncated input cannot force a huge allocation.
+const stringChunk = 64 << 10
+
+var unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+
+// A Decoder reads bencoded values from an input stream. It may read ahead of the value it
+// decodes, so it should be the only reader of its input.
+type Decoder struct {
+	// Limits bounds every value read by Decode. NewDecoder sets it to DefaultLimits.
+	Limits Limits
+	// Strict rejects input that is not in canonical form: integers and string lengths with
+	// leading zeros, negative zero, and dictionary keys that are not sorted or are repeated.
+	Strict bool
+
+	r      *bufio.Reader
+	offset int64
+	size   int64
+	depth  int
+	raw    *bytes.Buffer
+}
+
+// NewDecoder returns a Decoder reading from r with DefaultLimits.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{Limits: DefaultLimits, r: bufio.NewReader(r)}
+}
+
+// Unmarshal decodes the single bencoded value in data into v with DefaultLimits.
+func Unmarshal(data []byte, v interface{}) error {
+	d := NewDecoder(bytes.NewReader(data))
+	if err := d.Decode(v); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return d.ExpectEOF()
+}
+
+// ExpectEOF returns a SyntaxError if the input continues after the last decoded value.
+func (d *Decoder) ExpectEOF() error {
+	if _, err := d.r.ReadByte(); err != io.EOF {
+		return &SyntaxError{Msg: "trailing data after value", Offset: d.offset}
+	}
+	return nil
+}
+
+// Decode reads the next bencoded value from the input and stores it in v, which must be a
+// non-nil pointer. Integers, strings, lists and dictionaries are stored in an empty
+// interface as int64, string, []interface{} and map[string]interface{}. Dictionaries are
+// decoded into structs by matching keys against field tags exactly; unknown keys are
+// skipped. Decode returns io.EOF when the input ends before a value starts.
+func (d *Decoder) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("bencode: Decode requires a non-nil pointer, got %T", v)
+	}
+	if _, err := d.r.Peek(1); err != nil {
+		return err
+	}
+	d.size = 0
+	d.depth = 0
+	err := d.value(rv)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (d *Decoder) readByte() (byte, error) {
+	if err := d.consume(1); err != nil {
+		return 0, err
+	}
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	d.offset++
+	if d.raw != nil {
+		d.raw.WriteByte(c)
+	}
+	return c, nil
+}
+
+func (d *Decoder) peekByte() (byte, error) {
+	b, err := d.r.Peek(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// consume accounts for n more bytes of the current value against the size limit.
+func (d *Decoder) consume(n int64) error {
+	d.size += n
+	if d.Limits.MaxSize > 0 && d.size > d.Limits.MaxSize {
+		return fmt.Errorf("%w: value larger than %d bytes at offset %d", ErrLimitExceeded, d.Limits.MaxSize, d.offset)
+	}
+	return nil
+}
+
+// value decodes the next value into v, or skips it when v is invalid.
+func (d *Decoder) value(v reflect.Value) error {
+	if v.IsValid() {
+		u, target := indirect(v)
+		if u != nil {
+			return d.unmarshaler(u)
+		}
+		v = target
+	}
+
+	c, err := d.peekByte()
+	if err != nil {
+		return err
+	}
+	switch {
+	case c == 'i':
+		return d.integer(v)
+	case c >= '0' && c <= '9':
+		return d.string(v)
+	case c == 'l':
+		return d.list(v)
+	case c == 'd':
+		return d.dict(v)
+	}
+	return &SyntaxError{Msg: fmt.Sprintf("invalid character %q looking for beginning of value", c), Offset: d.offset}
+}
+
+// indirect walks down v allocating pointers as needed, stopping at an Unmarshaler.
+func indirect(v reflect.Value) (Unmarshaler, reflect.Value) {
+	for {
+		if v.Kind() != reflect.Pointer && v.CanAddr() && reflect.PointerTo(v.Type()).Implements(unmarshalerType) {
+			return v.Addr().Interface().(Unmarshaler), reflect.Value{}
+		}
+		if v.Kind() != reflect.Pointer {
+			return nil, v
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		if v.Type().Implements(unmarshalerType) {
+			return v.Interface().(Unmarshaler), reflect.Value{}
+		}
+		v = v.Elem()
+	}
+}
+
+// unmarshaler captures the exact bytes of the next value and hands them to u.
+func (d *Decoder) unmarshaler(u Unmarshaler) error {
+	var raw bytes.Buffer
+	previous := d.raw
+	d.raw = &raw
+	err := d.value(reflect.Value{})
+	d.raw = previous
+	if previous != nil {
+		previous.Write(raw.Bytes())
+	}
+	if err != nil {
+		return err
+	}
+	return u.UnmarshalBencode(raw.Bytes())
+}
+
+// readUntil reads the bytes before the delimiter, which is consumed but not returned.
+func (d *Decoder) readUntil(delim byte, maxLen int) ([]byte, error) {
+	var buf []byte
+	for {
+		c, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if c == delim {
+			return buf, nil
+		}
+		if len(buf) == maxLen {
+			return nil, &SyntaxError{Msg: fmt.Sprintf("number too long, expected %q", delim), Offset: d.offset - 1}
+		}
+		buf = append(buf, c)
+	}
+}
+
+// checkNumber validates the digits of an integer or string length.
+func (d *Decoder) checkNumber(digits []byte, signed bool, start int64) error {
+	unsigned := digits
+	if signed && len(digits) > 0 && digits[0] == '-' {
+		unsigned = digits[1:]
+	}
+	if len(unsigned) == 0 {
+		return &SyntaxError{Msg: "missing digits", Offset: start}
+	}
+	for _, c := range unsigned {
+		if c < '0' || c > '9' {
+			return &SyntaxError{Msg: fmt.Sprintf("invalid character %q in number", c), Offset: start}
+		}
+	}
+	if d.Strict && len(unsigned) > 1 && unsigned[0] == '0' {
+		return &SyntaxError{Msg: "number with leading zero", Offset: start}
+	}
+	if d.Strict && len(unsigned) != len(digits) && string(unsigned) == "0" {
+		return &SyntaxError{Msg: "negative zero", Offset: start}
+	}
+	return nil
+}
+
+func (d *Decoder) integer(v reflect.Value) error {
+	start := d.offset
+	d.readByte() // 'i'
+	digits, err := d.readUntil('e', 20)
+	if err != nil {
+		return err
+	}
+	if err := d.checkNumber(digits, true, start); err != nil {
+		return err
+	}
+	if !v.IsValid() {
+		return nil
+	}
+
+	s := string(digits)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v.OverflowInt(n) {
+			return d.typeError("integer "+s, v.Type(), start)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil || v.OverflowUint(n) {
+			return d.typeError("integer "+s, v.Type(), start)
+		}
+		v.SetUint(n)
+	case reflect.Bool:
+		switch s {
+		case "0":
+			v.SetBool(false)
+		case "1":
+			v.SetBool(true)
+		default:
+			return d.typeError("integer "+s, v.Type(), start)
+		}
+	case reflect.Interface:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v.NumMethod() != 0 {
+			return d.typeError("integer "+s, v.Type(), start)
+		}
+		v.Set(reflect.ValueOf(n))
+	default:
+		return d.typeError("integer", v.Type(), start)
+	}
+	return nil
+}
+
+// readString reads a length-prefixed string.
+func (d *Decoder) readString() ([]byte, error) {
+	start := d.offset
+	digits, err := d.readUntil(':', 19)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.checkNumber(digits, false, start); err != nil {
+		return nil, err
+	}
+	length, err := strconv.ParseInt(string(digits), 10, 64)
+	if err != nil {
+		return nil, &SyntaxError{Msg: "invalid string length", Offset: start}
+	}
+	if d.Limits.MaxStringLength > 0 && length > int64(d.Limits.MaxStringLength) {
+		return nil, fmt.Errorf("%w: string of %d bytes longer than %d at offset %d", ErrLimitExceeded, length, d.Limits.MaxStringLength, start)
+	}
+	if err := d.consume(length); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 0, min(length, stringChunk))
+	for int64(len(buf)) < length {
+		n := int(min(length-int64(len(buf)), stringChunk))
+		buf = slices.Grow(buf, n)
+		if _, err := io.ReadFull(d.r, buf[len(buf):len(buf)+n]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		buf = buf[:len(buf)+n]
+	}
+	d.offset += length
+	if d.raw != nil {
+		d.raw.Write(buf)
+	}
+	return buf, nil
+}
+
+func (d *Decoder) string(v reflect.Value) error {
+	start := d.offset
+	s, err := d.readString()
+	if err != nil || !v.IsValid() {
+		return err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(string(s))
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return d.typeError("string", v.Type(), start)
+		}
+		v.SetBytes(s)
+	case reflect.Array:
+		if v.Type().Elem().Kind() != reflect.Uint8 || v.Len() != len(s) {
+			return d.typeError(fmt.Sprintf("string of length %d", len(s)), v.Type(), start)
+		}
+		reflect.Copy(v, reflect.ValueOf(s))
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return d.typeError("string", v.Type(), start)
+		}
+		v.Set(reflect.ValueOf(string(s)))
+	default:
+		return d.typeError("string", v.Type(), start)
+	}
+	return nil
+}
+
+func (d *Decoder) enter() error {
+	d.depth++
+	if d.Limits.MaxDepth > 0 && d.depth > d.Limits.MaxDepth {
+		return fmt.Errorf("%w: nesting deeper than %d at offset %d", ErrLimitExceeded, d.Limits.MaxDepth, d.offset)
+	}
+	_, err := d.readByte()
+	return err
+}
+
+// atEnd consumes the 'e' closing a list or dictionary, telling if it was found.
+func (d *Decoder) atEnd() (bool, error) {
+	c, err := d.peekByte()
+	if err != nil {
+		return false, err
+	}
+	if c != 'e' {
+		return false, nil
+	}
+	d.readByte()
+	d.depth--
+	return true, nil
+}
+
+func (d *Decoder) list(v reflect.Value) error {
+	start := d.offset
+	if err := d.enter(); err != nil {
+		return err
+	}
+
+	var elems []interface{}
+	switch {
+	case !v.IsValid():
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8:
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	case v.Kind() == reflect.Array && v.Type().Elem().Kind() != reflect.Uint8:
+	case v.Kind() == reflect.Interface && v.NumMethod() == 0:
+		elems = []interface{}{}
+	default:
+		return d.typeError("list", v.Type(), start)
+	}
+
+	for i := 0; ; i++ {
+		end, err := d.atEnd()
+		if err != nil {
+			return err
+		}
+		if end {
+			break
+		}
+		switch {
+		case !v.IsValid():
+			err = d.value(reflect.Value{})
+		case v.Kind() == reflect.Slice:
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err = d.value(elem); err == nil {
+				v.Set(reflect.Append(v, elem))
+			}
+		case v.Kind() == reflect.Array:
+			if i < v.Len() {
+				err = d.value(v.Index(i))
+			} else {
+				err = d.value(reflect.Value{})
+			}
+		default:
+			var elem interface{}
+			if err = d.value(reflect.ValueOf(&elem).Elem()); err == nil {
+				elems = append(elems, elem)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if elems != nil {
+		v.Set(reflect.ValueOf(elems))
+	}
+	return nil
+}
+
+func (d *Decoder) dict(v reflect.Value) error {
+	start := d.offset
+	if err := d.enter(); err != nil {
+		return err
+	}
+
+	var fields []field
+	var generic map[string]interface{}
+	switch {
+	case !v.IsValid():
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+	case v.Kind() == reflect.Struct:
+		fields = structFields(v.Type())
+	case v.Kind() == reflect.Interface && v.NumMethod() == 0:
+		generic = make(map[string]interface{})
+	default:
+		return d.typeError("dictionary", v.Type(), start)
+	}
+
+	var previous []byte
+	for first := true; ; first = false {
+		end, err := d.atEnd()
+		if err != nil {
+			return err
+		}
+		if end {
+			break
+		}
+		keyStart := d.offset
+		if c, _ := d.peekByte(); c < '0' || c > '9' {
+			return &SyntaxError{Msg: "dictionary key is not a string", Offset: keyStart}
+		}
+		key, err := d.readString()
+		if err != nil {
+			return err
+		}
+		if d.Strict && !first && bytes.Compare(previous, key) >= 0 {
+			return &SyntaxError{Msg: fmt.Sprintf("dictionary key %q is not sorted or repeated", key), Offset: keyStart}
+		}
+		previous = key
+
+		switch {
+		case !v.IsValid():
+			err = d.value(reflect.Value{})
+		case generic != nil:
+			var elem interface{}
+			if err = d.value(reflect.ValueOf(&elem).Elem()); err == nil {
+				generic[string(key)] = elem
+			}
+		case v.Kind() == reflect.Map:
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err = d.value(elem); err == nil {
+				v.SetMapIndex(reflect.ValueOf(string(key)).Convert(v.Type().Key()), elem)
+			}
+		default:
+			target := reflect.Value{}
+			for _, f := range fields {
+				if f.name == string(key) {
+					target = v.Field(f.index)
+					break
+				}
+			}
+			err = d.value(target)
+		}
+		if err != nil {
+			var typeErr *UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				if typeErr.Field == "" {
+					typeErr.Field = string(key)
+				} else {
+					typeErr.Field = string(key) + "." + typeErr.Field
+				}
+			}
+			return err
+		}
+	}
+
+	if generic != nil {
+		v.Set(reflect.ValueOf(generic))
+	}
+	return nil
+}
+
+// typeError reports a value at offset that cannot be stored in a Go value of type t.
+func (d *Decoder) typeError(value string, t reflect.Type, offset int64) error {
+	return &UnmarshalTypeError{Value: value, Type: t, Offset: offset}
+}
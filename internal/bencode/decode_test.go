@@ -0,0 +1,237 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalGeneric(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		expected interface{}
+	}{
+		{"Integer", "i42e", int64(42)},
+		{"NegativeInteger", "i-7e", int64(-7)},
+		{"String", "4:spam", "spam"},
+		{"EmptyString", "0:", ""},
+		{"List", "l4:spami42ee", []interface{}{"spam", int64(42)}},
+		{"EmptyList", "le", []interface{}{}},
+		{"NestedDictionary", "d3:bard1:xli1eeee", map[string]interface{}{"bar": map[string]interface{}{"x": []interface{}{int64(1)}}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var v interface{}
+			if err := Unmarshal([]byte(tc.data), &v); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if !reflect.DeepEqual(v, tc.expected) {
+				t.Errorf("Unexpected value: got %#v, want %#v", v, tc.expected)
+			}
+		})
+	}
+}
+
+func TestUnmarshalStruct(t *testing.T) {
+	type file struct {
+		Length int64    `bencode:"length"`
+		Path   []string `bencode:"path"`
+	}
+	type info struct {
+		Name     string            `bencode:"name"`
+		Files    []file            `bencode:"files"`
+		Private  bool              `bencode:"private"`
+		Hash     [4]byte           `bencode:"hash"`
+		Extra    map[string]string `bencode:"extra"`
+		Count    *uint16           `bencode:"count"`
+		Ignored  string            `bencode:"-"`
+		Raw      RawMessage        `bencode:"raw"`
+		Untagged int
+	}
+
+	data := "d5:counti7e5:extrad1:a1:be5:filesld6:lengthi3e4:pathl1:a1:beee4:hash4:abcd" +
+		"1:-3:xyz4:name4:root7:privatei1e3:rawd1:xi1ee7:unknownli1ei2ee8:Untaggedi9ee"
+	var got info
+	if err := Unmarshal([]byte(data), &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	count := uint16(7)
+	expected := info{
+		Name:     "root",
+		Files:    []file{{Length: 3, Path: []string{"a", "b"}}},
+		Private:  true,
+		Hash:     [4]byte{'a', 'b', 'c', 'd'},
+		Extra:    map[string]string{"a": "b"},
+		Count:    &count,
+		Raw:      RawMessage("d1:xi1ee"),
+		Untagged: 9,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected value: got %+v, want %+v", got, expected)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	testCases := []struct {
+		name       string
+		data       string
+		target     interface{}
+		errMessage string
+	}{
+		{"Empty", "", new(interface{}), "unexpected EOF"},
+		{"Truncated", "d8:announce", new(interface{}), "unexpected EOF"},
+		{"TruncatedString", "10:abc", new(interface{}), "unexpected EOF"},
+		{"TrailingData", "i1ei2e", new(interface{}), "bencode: trailing data after value at offset 3"},
+		{"InvalidCharacter", "x", new(interface{}), "bencode: invalid character 'x' looking for beginning of value at offset 0"},
+		{"EmptyInteger", "ie", new(interface{}), "bencode: missing digits at offset 0"},
+		{"InvalidInteger", "i1a2e", new(interface{}), "bencode: invalid character 'a' in number at offset 0"},
+		{"NonStringKey", "di1ei2ee", new(interface{}), "bencode: dictionary key is not a string at offset 1"},
+		{"IntegerOverflow", "i300e", new(uint8), "bencode: cannot unmarshal integer 300 into Go value of type uint8 at offset 0"},
+		{"WrongType", "d4:namei1ee", &struct {
+			Name string `bencode:"name"`
+		}{}, "bencode: cannot unmarshal integer into field \"name\" of type string at offset 7"},
+		{"WrongArrayLength", "3:abc", new([4]byte), "bencode: cannot unmarshal string of length 3 into Go value of type [4]uint8 at offset 0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Unmarshal([]byte(tc.data), tc.target)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if err.Error() != tc.errMessage {
+				t.Errorf("Unexpected error message: got %q, want %q", err.Error(), tc.errMessage)
+			}
+		})
+	}
+}
+
+func TestDecoderLimits(t *testing.T) {
+	testCases := []struct {
+		name   string
+		data   string
+		limits Limits
+	}{
+		{"Depth", strings.Repeat("l", 5) + strings.Repeat("e", 5), Limits{MaxDepth: 4}},
+		{"StringLength", "5:hello", Limits{MaxStringLength: 4}},
+		{"HugeDeclaredString", "999999999999:a", DefaultLimits},
+		{"Size", "l5:hello5:worlde", Limits{MaxSize: 10}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := NewDecoder(strings.NewReader(tc.data))
+			d.Limits = tc.limits
+			var v interface{}
+			if err := d.Decode(&v); !errors.Is(err, ErrLimitExceeded) {
+				t.Errorf("Unexpected error: got %v, want %v", err, ErrLimitExceeded)
+			}
+		})
+	}
+
+	t.Run("WithinLimits", func(t *testing.T) {
+		d := NewDecoder(strings.NewReader("l5:hello5:worlde"))
+		d.Limits = Limits{MaxDepth: 1, MaxStringLength: 5, MaxSize: 16}
+		var v interface{}
+		if err := d.Decode(&v); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+}
+
+func TestDecoderStrict(t *testing.T) {
+	testCases := []struct {
+		name       string
+		data       string
+		errMessage string
+	}{
+		{"LeadingZeroInteger", "i03e", "bencode: number with leading zero at offset 0"},
+		{"NegativeZero", "i-0e", "bencode: negative zero at offset 0"},
+		{"LeadingZeroLength", "02:ab", "bencode: number with leading zero at offset 0"},
+		{"UnsortedKeys", "d1:bi1e1:ai2ee", "bencode: dictionary key \"a\" is not sorted or repeated at offset 7"},
+		{"DuplicateKeys", "d1:ai1e1:ai2ee", "bencode: dictionary key \"a\" is not sorted or repeated at offset 7"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var v interface{}
+			if err := Unmarshal([]byte(tc.data), &v); err != nil {
+				t.Fatalf("Lenient decoding failed: %v", err)
+			}
+
+			d := NewDecoder(strings.NewReader(tc.data))
+			d.Strict = true
+			err := d.Decode(&v)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if err.Error() != tc.errMessage {
+				t.Errorf("Unexpected error message: got %q, want %q", err.Error(), tc.errMessage)
+			}
+		})
+	}
+}
+
+func TestDecoderStream(t *testing.T) {
+	d := NewDecoder(strings.NewReader("i1e4:spamle"))
+	var values []interface{}
+	for {
+		var v interface{}
+		err := d.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		values = append(values, v)
+	}
+	expected := []interface{}{int64(1), "spam", []interface{}{}}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("Unexpected values: got %#v, want %#v", values, expected)
+	}
+	if err := d.ExpectEOF(); err != nil {
+		t.Errorf("Unexpected error at end of input: %v", err)
+	}
+
+	t.Run("TrailingData", func(t *testing.T) {
+		d := NewDecoder(strings.NewReader("i1ex"))
+		var v interface{}
+		if err := d.Decode(&v); err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		expected := "bencode: trailing data after value at offset 3"
+		if err := d.ExpectEOF(); err == nil || err.Error() != expected {
+			t.Errorf("Unexpected error: got %v, want %q", err, expected)
+		}
+	})
+}
+
+func TestRawMessageRoundTrip(t *testing.T) {
+	// The info dictionary is not canonical, so only a raw copy preserves its hash.
+	data := []byte("d4:infod4:name1:a6:lengthi01ee3:zzzi1ee")
+	var metainfo struct {
+		Info RawMessage `bencode:"info"`
+		Zzz  int        `bencode:"zzz"`
+	}
+	if err := Unmarshal(data, &metainfo); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if string(metainfo.Info) != "d4:name1:a6:lengthi01ee" {
+		t.Errorf("Unexpected raw value: got %q", metainfo.Info)
+	}
+
+	encoded, err := Marshal(metainfo)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !bytes.Equal(encoded, data) {
+		t.Errorf("Unexpected encoding: got %q, want %q", encoded, data)
+	}
+}
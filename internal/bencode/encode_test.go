@@ -0,0 +1,101 @@
+package bencode
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	type file struct {
+		Path   []string `bencode:"path"`
+		Length int      `bencode:"length"`
+	}
+	type info struct {
+		Pieces  string                 `bencode:"pieces"`
+		Name    string                 `bencode:"name"`
+		Files   []file                 `bencode:"files,omitempty"`
+		Length  int                    `bencode:"length,omitempty"`
+		Private bool                   `bencode:"private,omitempty"`
+		Tree    map[string]interface{} `bencode:"file tree,omitempty"`
+		Skipped string                 `bencode:"-"`
+	}
+
+	testCases := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{"Integer", -42, "i-42e"},
+		{"Unsigned", uint64(1 << 63), "i9223372036854775808e"},
+		{"Bool", true, "i1e"},
+		{"String", "spam", "4:spam"},
+		{"Bytes", []byte{0, 1}, "2:\x00\x01"},
+		{"ByteArray", [2]byte{'h', 'i'}, "2:hi"},
+		{"List", []interface{}{"a", 1, []string{}}, "l1:ai1elee"},
+		{"SortedMap", map[string]int{"b": 2, "a": 1, "A": 0}, "d1:Ai0e1:ai1e1:bi2ee"},
+		{
+			"StructSortedWithOmitEmpty",
+			info{Pieces: "xx", Name: "root", Files: []file{{Path: []string{"a"}, Length: 3}}, Skipped: "no"},
+			"d5:filesld6:lengthi3e4:pathl1:aeee4:name4:root6:pieces2:xxe",
+		},
+		{
+			"NestedInterfaceMap",
+			info{Tree: map[string]interface{}{"b": map[string]interface{}{"": int64(1)}}},
+			"d9:file treed1:bd0:i1eee4:name0:6:pieces0:e",
+		},
+		{"RawMessage", []RawMessage{RawMessage("i1e"), RawMessage("le")}, "li1elee"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Marshal(tc.value)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if string(got) != tc.expected {
+				t.Errorf("Unexpected encoding: got %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	testCases := []struct {
+		name       string
+		value      interface{}
+		errMessage string
+	}{
+		{"Float", 1.5, "bencode: unsupported type float64"},
+		{"IntegerKeys", map[int]string{1: "a"}, "bencode: unsupported type map[int]string"},
+		{"NilPointer", (*int)(nil), "bencode: cannot marshal nil *int"},
+		{"InvalidRawMessage", RawMessage("i1"), "bencode: bencode.RawMessage.MarshalBencode returned invalid bencode"},
+		{"EmptyRawMessage", RawMessage(nil), "bencode: empty RawMessage"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Marshal(tc.value)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if err.Error() != tc.errMessage {
+				t.Errorf("Unexpected error message: got %q, want %q", err.Error(), tc.errMessage)
+			}
+		})
+	}
+}
+
+func TestEncoderRoundTrip(t *testing.T) {
+	data := "d8:announce3:url4:infod5:filesld6:lengthi1e4:pathl1:aeee4:name1:r12:piece lengthi16384eee"
+	var v interface{}
+	if err := Unmarshal([]byte(data), &v); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(v); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if buf.String() != data {
+		t.Errorf("Unexpected encoding: got %q, want %q", buf.String(), data)
+	}
+}
@@ -14,8 +14,10 @@ const (
 // Handshake represents a handshake message.
 type Handshake struct {
 	ProtocolString string
-	InfoHash       [InfoHashLength]byte
-	PeerID         [PeerIDLength]byte
+	// Reserved holds the bits peers set to advertise protocol extensions.
+	Reserved [ReservedBufSize]byte
+	InfoHash [InfoHashLength]byte
+	PeerID   [PeerIDLength]byte
 }
 
 // NewHandshake creates a new Handshake with the given info hash and peer ID.
@@ -33,7 +35,7 @@ func (h *Handshake) Serialize() []byte {
 	buf[0] = byte(len(h.ProtocolString))
 	curr := 1
 	curr += copy(buf[curr:], h.ProtocolString)
-	curr += copy(buf[curr:], make([]byte, ReservedBufSize))
+	curr += copy(buf[curr:], h.Reserved[:])
 	curr += copy(buf[curr:], h.InfoHash[:])
 	curr += copy(buf[curr:], h.PeerID[:])
 	return buf
@@ -42,27 +44,29 @@ func (h *Handshake) Serialize() []byte {
 // ReadHandshake parses a Handshake from an io.Reader.
 func ReadHandshake(r io.Reader) (*Handshake, error) {
 	protocolStringLenBuf := make([]byte, 1)
-	_, err := r.Read(protocolStringLenBuf)
+	_, err := io.ReadFull(r, protocolStringLenBuf)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read ProtocolString length: %w", err)
 	}
 	protocolStringLen := int(protocolStringLenBuf[0])
 
 	protocolStringBuf := make([]byte, protocolStringLen)
-	_, err = r.Read(protocolStringBuf)
+	_, err = io.ReadFull(r, protocolStringBuf)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read ProtocolString: %w", err)
 	}
 	protocolString := string(protocolStringBuf)
 
 	reservedBuf := make([]byte, ReservedBufSize)
-	_, err = r.Read(reservedBuf)
+	_, err = io.ReadFull(r, reservedBuf)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read reserved bytes: %w", err)
 	}
+	var reserved [ReservedBufSize]byte
+	copy(reserved[:], reservedBuf)
 
 	infoHashBuf := make([]byte, InfoHashLength)
-	_, err = r.Read(infoHashBuf)
+	_, err = io.ReadFull(r, infoHashBuf)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read InfoHash: %w", err)
 	}
@@ -70,7 +74,7 @@ func ReadHandshake(r io.Reader) (*Handshake, error) {
 	copy(infoHash[:], infoHashBuf)
 
 	peerIDBuf := make([]byte, PeerIDLength)
-	_, err = r.Read(peerIDBuf)
+	_, err = io.ReadFull(r, peerIDBuf)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read PeerID: %w", err)
 	}
@@ -79,6 +83,7 @@ func ReadHandshake(r io.Reader) (*Handshake, error) {
 
 	return &Handshake{
 		ProtocolString: protocolString,
+		Reserved:       reserved,
 		InfoHash:       infoHash,
 		PeerID:         peerID,
 	}, nil
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"reflect"
 	"testing"
+	"testing/iotest"
 )
 
 func TestHandshake_Serialize(t *testing.T) {
@@ -71,13 +72,23 @@ func TestHandshake_SerializeAndRead(t *testing.T) {
 				PeerID:         [20]byte{21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34, 35, 36, 37, 38, 39, 40},
 			},
 		},
+		{
+			name: "ReservedBits",
+			handshake: &Handshake{
+				ProtocolString: "BitTorrent protocol",
+				Reserved:       [8]byte{0, 0, 0, 0, 0, 0x10, 0, 0x05},
+				InfoHash:       [20]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20},
+				PeerID:         [20]byte{21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34, 35, 36, 37, 38, 39, 40},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			serializedHandshake := tc.handshake.Serialize()
 
-			reader := bytes.NewReader(serializedHandshake)
+			// Reading a byte at a time checks that short reads are completed.
+			reader := iotest.OneByteReader(bytes.NewReader(serializedHandshake))
 
 			readHandshake, err := ReadHandshake(reader)
 			if err != nil {
@@ -1,9 +1,15 @@
 package peer
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
+	"fmt"
+	"io"
 	"net"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/mattheworford/gotorrent/internal/message"
 )
@@ -11,6 +17,25 @@ import (
 const (
 	peerSize   = 6
 	portOffset = 4
+
+	peer6Size   = 18
+	port6Offset = 16
+)
+
+// Deadlines for dialing a peer, and for the handshake and bitfield that follow. A peer that
+// sends nothing for bitfieldTimeout after its handshake is taken to have no pieces.
+var (
+	connectTimeout   = 5 * time.Second
+	handshakeTimeout = 10 * time.Second
+	bitfieldTimeout  = 2 * time.Second
+)
+
+var (
+	// ErrInfoHashMismatch is returned when a peer answers a handshake for another torrent.
+	ErrInfoHashMismatch = errors.New("peer handshake has a different info hash")
+	// ErrSelfConnection is returned when a peer answers with our own peer ID, as happens
+	// when a tracker lists our own address.
+	ErrSelfConnection = errors.New("peer handshake has our own peer ID")
 )
 
 // ConnectionInfo represents connection information for a peer.
@@ -19,6 +44,11 @@ type ConnectionInfo struct {
 	Port uint16
 }
 
+// String returns the address of the peer in host:port form.
+func (c ConnectionInfo) String() string {
+	return net.JoinHostPort(c.IP.String(), strconv.Itoa(int(c.Port)))
+}
+
 // Client represents a peer client.
 type Client struct {
 	Conn           net.Conn
@@ -27,6 +57,117 @@ type Client struct {
 	ConnectionInfo ConnectionInfo
 	InfoHash       [20]byte
 	PeerID         [20]byte
+	// RemotePeerID and Reserved are the peer ID and reserved bits of the peer's handshake.
+	RemotePeerID [20]byte
+	Reserved     [message.ReservedBufSize]byte
+	// pending is the first message after the handshake when it was not a bitfield.
+	pending *message.PeerMessage
+}
+
+// Dial connects to a peer, exchanges handshakes for a torrent and reads the bitfield the
+// peer sends first. Keep-alives before the bitfield are skipped. A peer with no pieces may
+// skip the bitfield (BEP 3), so another first message, or a short silence, leaves Bitfield
+// empty; that message is then returned by the first ReadMessage. The returned client starts
+// out choked.
+func Dial(ctx context.Context, info ConnectionInfo, infoHash, peerID [20]byte) (*Client, error) {
+	conn, err := info.DialContext(ctx, &net.Dialer{Timeout: connectTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", info, err)
+	}
+	client := &Client{
+		Conn:           conn,
+		Choked:         true,
+		ConnectionInfo: info,
+		InfoHash:       infoHash,
+		PeerID:         peerID,
+	}
+	if err := client.handshake(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to handshake with %s: %w", info, err)
+	}
+	return client, nil
+}
+
+func (c *Client) handshake(ctx context.Context) error {
+	deadline := time.Now().Add(handshakeTimeout)
+	c.Conn.SetDeadline(deadline)
+	defer c.Conn.SetDeadline(time.Time{})
+	stop := context.AfterFunc(ctx, func() { c.Conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if _, err := c.Conn.Write(message.NewHandshake(c.InfoHash, c.PeerID).Serialize()); err != nil {
+		return contextError(ctx, err)
+	}
+	reply, err := message.ReadHandshake(c.Conn)
+	if err != nil {
+		return contextError(ctx, err)
+	}
+	if reply.ProtocolString != "BitTorrent protocol" {
+		return fmt.Errorf("unexpected protocol %q", reply.ProtocolString)
+	}
+	if reply.InfoHash != c.InfoHash {
+		return ErrInfoHashMismatch
+	}
+	if reply.PeerID == c.PeerID {
+		return ErrSelfConnection
+	}
+	c.RemotePeerID = reply.PeerID
+	c.Reserved = reply.Reserved
+
+	if bitfieldDeadline := time.Now().Add(bitfieldTimeout); bitfieldDeadline.Before(deadline) {
+		c.Conn.SetReadDeadline(bitfieldDeadline)
+	}
+	for {
+		r := &countingReader{r: c.Conn}
+		msg, err := message.ReadPeerMessage(r)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) && r.n == 0 && ctx.Err() == nil {
+				c.Bitfield = message.Bitfield{}
+				return nil
+			}
+			return contextError(ctx, err)
+		}
+		if msg == nil {
+			continue
+		}
+		if msg.Type == message.BitfieldMessage {
+			c.Bitfield = message.Bitfield(msg.Payload)
+		} else {
+			c.Bitfield = message.Bitfield{}
+			c.pending = msg
+		}
+		return nil
+	}
+}
+
+// ReadMessage reads the next message from the peer, starting with any message Dial read
+// in place of a bitfield. A nil message is a keep-alive.
+func (c *Client) ReadMessage() (*message.PeerMessage, error) {
+	if msg := c.pending; msg != nil {
+		c.pending = nil
+		return msg, nil
+	}
+	return message.ReadPeerMessage(c.Conn)
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += n
+	return n, err
+}
+
+// contextError prefers the context's error to one caused by its cancellation.
+func contextError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
 }
 
 // DecodeConnectionInfo parses peer IP addresses and ports from binary data.
@@ -56,3 +197,64 @@ func DecodeConnectionInfo(peerData []byte) ([]ConnectionInfo, error) {
 
 	return peers, nil
 }
+
+// DecodeConnectionInfo6 parses peer IPv6 addresses and ports from the 18-byte compact
+// entries of a peers6 list (BEP 7).
+func DecodeConnectionInfo6(peerData []byte) ([]ConnectionInfo, error) {
+	if peerData == nil {
+		return nil, errors.New("input data is nil")
+	}
+
+	if len(peerData)%peer6Size != 0 {
+		return nil, errors.New("malformed connection data: incorrect size")
+	}
+
+	peers := make([]ConnectionInfo, len(peerData)/peer6Size)
+	for i := range peers {
+		offset := i * peer6Size
+		ip := make(net.IP, net.IPv6len)
+		copy(ip, peerData[offset:offset+port6Offset])
+		peers[i] = ConnectionInfo{
+			IP:   ip,
+			Port: binary.BigEndian.Uint16(peerData[offset+port6Offset : offset+peer6Size]),
+		}
+	}
+	return peers, nil
+}
+
+// EncodeConnectionInfo writes peers in compact form, split by address family: 6-byte
+// entries for IPv4 peers and 18-byte entries for IPv6 peers. Peers without a valid IP
+// address are skipped.
+func EncodeConnectionInfo(peers []ConnectionInfo) (ipv4, ipv6 []byte) {
+	for _, p := range peers {
+		if ip := p.IP.To4(); ip != nil {
+			ipv4 = binary.BigEndian.AppendUint16(append(ipv4, ip...), p.Port)
+		} else if ip := p.IP.To16(); ip != nil {
+			ipv6 = binary.BigEndian.AppendUint16(append(ipv6, ip...), p.Port)
+		}
+	}
+	return ipv4, ipv6
+}
+
+// Network returns the network for connecting to the peer: "tcp4" for IPv4 addresses,
+// including IPv4-mapped IPv6 ones, and "tcp6" otherwise.
+func (c ConnectionInfo) Network() string {
+	if c.IP.To4() != nil {
+		return "tcp4"
+	}
+	return "tcp6"
+}
+
+// DialContext opens a TCP connection to the peer over IPv4 or IPv6 as its address requires.
+func (c ConnectionInfo) DialContext(ctx context.Context, dialer *net.Dialer) (net.Conn, error) {
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	return dialer.DialContext(ctx, c.Network(), c.String())
+}
+
+// Listen accepts peer connections on the given port of every local IPv4 and IPv6 address.
+// A port of zero picks a free one.
+func Listen(port uint16) (net.Listener, error) {
+	return net.Listen("tcp", net.JoinHostPort("", strconv.Itoa(int(port))))
+}
@@ -1,9 +1,16 @@
 package peer
 
 import (
+	"context"
+	"errors"
+	"io"
 	"net"
+	"os"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/message"
 )
 
 func TestDecodeConnectionInfo(t *testing.T) {
@@ -59,3 +66,273 @@ func TestDecodeConnectionInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeConnectionInfo6(t *testing.T) {
+	testCases := []struct {
+		name       string
+		peerData   []byte
+		expected   []ConnectionInfo
+		errMessage string
+	}{
+		{
+			name: "ValidPeerData",
+			peerData: []byte{
+				0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0x1a, 0xe1,
+				0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 192, 168, 0, 1, 0, 80,
+			},
+			expected: []ConnectionInfo{
+				{IP: net.ParseIP("2001:db8::1"), Port: 6881},
+				{IP: net.ParseIP("::ffff:192.168.0.1"), Port: 80},
+			},
+		},
+		{
+			name:       "NilPeerData",
+			peerData:   nil,
+			errMessage: "input data is nil",
+		},
+		{
+			name:       "MalformedPeerData",
+			peerData:   make([]byte, 12),
+			errMessage: "malformed connection data: incorrect size",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			peers, err := DecodeConnectionInfo6(tc.peerData)
+			if tc.errMessage != "" {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				} else if err.Error() != tc.errMessage {
+					t.Errorf("Unexpected error message: got %q, want %q", err.Error(), tc.errMessage)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(peers, tc.expected) {
+				t.Errorf("Unexpected result. Expected: %v, Got: %v", tc.expected, peers)
+			}
+		})
+	}
+}
+
+func TestConnectionInfoNetwork(t *testing.T) {
+	testCases := []struct {
+		name     string
+		info     ConnectionInfo
+		network  string
+		expected string
+	}{
+		{"IPv4", ConnectionInfo{IP: net.IPv4(10, 0, 0, 1), Port: 6881}, "tcp4", "10.0.0.1:6881"},
+		{"IPv4Mapped", ConnectionInfo{IP: net.ParseIP("::ffff:10.0.0.1"), Port: 6881}, "tcp4", "10.0.0.1:6881"},
+		{"IPv6", ConnectionInfo{IP: net.ParseIP("2001:db8::1"), Port: 6881}, "tcp6", "[2001:db8::1]:6881"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.info.Network(); got != tc.network {
+				t.Errorf("Unexpected network: got %s, want %s", got, tc.network)
+			}
+			if got := tc.info.String(); got != tc.expected {
+				t.Errorf("Unexpected address: got %s, want %s", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestListenAndDial(t *testing.T) {
+	listener, err := Listen(0)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("ok"))
+			conn.Close()
+		}
+	}()
+	port := uint16(listener.Addr().(*net.TCPAddr).Port)
+
+	for _, ip := range []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback} {
+		t.Run(ip.String(), func(t *testing.T) {
+			if ip.To4() == nil {
+				if probe, err := net.Listen("tcp6", "[::1]:0"); err != nil {
+					t.Skipf("IPv6 loopback unavailable: %v", err)
+				} else {
+					probe.Close()
+				}
+			}
+			conn, err := ConnectionInfo{IP: ip, Port: port}.DialContext(context.Background(), nil)
+			if err != nil {
+				t.Fatalf("Dial failed: %v", err)
+			}
+			defer conn.Close()
+			buf := make([]byte, 2)
+			if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ok" {
+				t.Errorf("Unexpected reply: got %q, %v", buf, err)
+			}
+		})
+	}
+}
+
+func TestEncodeConnectionInfo(t *testing.T) {
+	peers := []ConnectionInfo{
+		{IP: net.IPv4(192, 168, 0, 1), Port: 80},
+		{IP: net.ParseIP("2001:db8::1"), Port: 6881},
+		{IP: nil, Port: 1},
+		{IP: net.ParseIP("::ffff:10.0.0.2"), Port: 81},
+	}
+	ipv4, ipv6 := EncodeConnectionInfo(peers)
+
+	decoded4, err := DecodeConnectionInfo(ipv4)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := []ConnectionInfo{peers[0], {IP: net.IPv4(10, 0, 0, 2), Port: 81}}; !reflect.DeepEqual(decoded4, expected) {
+		t.Errorf("Unexpected IPv4 peers. Expected: %v, Got: %v", expected, decoded4)
+	}
+	decoded6, err := DecodeConnectionInfo6(ipv6)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := []ConnectionInfo{peers[1]}; !reflect.DeepEqual(decoded6, expected) {
+		t.Errorf("Unexpected IPv6 peers. Expected: %v, Got: %v", expected, decoded6)
+	}
+}
+
+// servePeer accepts one connection on loopback and hands it to respond.
+func servePeer(t *testing.T, respond func(conn net.Conn)) ConnectionInfo {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		respond(conn)
+	}()
+	return ConnectionInfo{IP: net.IPv4(127, 0, 0, 1), Port: uint16(listener.Addr().(*net.TCPAddr).Port)}
+}
+
+func TestDial(t *testing.T) {
+	defer func(timeout time.Duration) { handshakeTimeout = timeout }(handshakeTimeout)
+	handshakeTimeout = 100 * time.Millisecond
+	defer func(timeout time.Duration) { bitfieldTimeout = timeout }(bitfieldTimeout)
+	bitfieldTimeout = 50 * time.Millisecond
+
+	infoHash := [20]byte{1, 2, 3}
+	peerID := [20]byte{'-', 'G', 'T'}
+	remoteID := [20]byte{'-', 'X', 'X'}
+	reserved := [8]byte{0, 0, 0, 0, 0, 0x10, 0, 0x05}
+
+	// answer reads our handshake and replies with one for the given torrent and peer,
+	// followed by the given messages.
+	answer := func(infoHash, peerID [20]byte, messages ...*message.PeerMessage) func(net.Conn) {
+		return func(conn net.Conn) {
+			if _, err := message.ReadHandshake(conn); err != nil {
+				return
+			}
+			reply := message.NewHandshake(infoHash, peerID)
+			reply.Reserved = reserved
+			conn.Write(reply.Serialize())
+			for _, msg := range messages {
+				buf, _ := msg.Serialize()
+				conn.Write(buf)
+			}
+			io.Copy(io.Discard, conn)
+		}
+	}
+	bitfield := &message.PeerMessage{Type: message.BitfieldMessage, Payload: []byte{0xa0, 0x01}}
+
+	unchoke := &message.PeerMessage{Type: message.UnchokeMessage, Payload: []byte{}}
+
+	testCases := []struct {
+		name     string
+		respond  func(net.Conn)
+		bitfield message.Bitfield
+		pending  *message.PeerMessage
+		fails    bool
+		// expectedError, if set, is the error a failure must wrap.
+		expectedError error
+	}{
+		{"Bitfield", answer(infoHash, remoteID, bitfield), message.Bitfield{0xa0, 0x01}, nil, false, nil},
+		{"KeepAliveFirst", answer(infoHash, remoteID, nil, bitfield), message.Bitfield{0xa0, 0x01}, nil, false, nil},
+		{"InfoHashMismatch", answer([20]byte{9}, remoteID, bitfield), nil, nil, true, ErrInfoHashMismatch},
+		{"SelfConnection", answer(infoHash, peerID, bitfield), nil, nil, true, ErrSelfConnection},
+		{"NoBitfield", answer(infoHash, remoteID, unchoke), message.Bitfield{}, unchoke, false, nil},
+		{"SilentPeer", func(conn net.Conn) { io.Copy(io.Discard, conn) }, nil, nil, true, os.ErrDeadlineExceeded},
+		{"SilentAfterHandshake", answer(infoHash, remoteID), message.Bitfield{}, nil, false, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := servePeer(t, tc.respond)
+			client, err := Dial(context.Background(), info, infoHash, peerID)
+			if tc.fails {
+				if err == nil || (tc.expectedError != nil && !errors.Is(err, tc.expectedError)) {
+					t.Fatalf("Unexpected error: got %v, want %v", err, tc.expectedError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Dial failed: %v", err)
+			}
+			defer client.Conn.Close()
+
+			expected := &Client{
+				Conn:           client.Conn,
+				Choked:         true,
+				Bitfield:       tc.bitfield,
+				ConnectionInfo: info,
+				InfoHash:       infoHash,
+				PeerID:         peerID,
+				RemotePeerID:   remoteID,
+				Reserved:       reserved,
+				pending:        tc.pending,
+			}
+			if !reflect.DeepEqual(client, expected) {
+				t.Errorf("Unexpected client: got %+v, want %+v", client, expected)
+			}
+			if tc.pending != nil {
+				if msg, err := client.ReadMessage(); err != nil || !reflect.DeepEqual(msg, tc.pending) {
+					t.Errorf("Unexpected first message: got %+v, %v, want %+v", msg, err, tc.pending)
+				}
+			}
+		})
+	}
+
+	t.Run("Cancelled", func(t *testing.T) {
+		info := servePeer(t, func(conn net.Conn) { io.Copy(io.Discard, conn) })
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		defer func(timeout time.Duration) { handshakeTimeout = timeout }(handshakeTimeout)
+		handshakeTimeout = time.Minute
+		if _, err := Dial(ctx, info, infoHash, peerID); !errors.Is(err, context.Canceled) {
+			t.Errorf("Unexpected error: got %v, want %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("Refused", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Listen failed: %v", err)
+		}
+		info := ConnectionInfo{IP: net.IPv4(127, 0, 0, 1), Port: uint16(listener.Addr().(*net.TCPAddr).Port)}
+		listener.Close()
+		if _, err := Dial(context.Background(), info, infoHash, peerID); err == nil {
+			t.Error("Expected an error dialing a closed port")
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package peer
+
+import "sync"
+
+// Source identifies where the address of a peer was learned.
+type Source uint8
+
+const (
+	SourceTracker Source = iota // 0
+	SourceDHT                   // 1
+	SourcePEX                   // 2
+	SourceLSD                   // 3
+	SourceMagnet                // 4
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceTracker:
+		return "tracker"
+	case SourceDHT:
+		return "dht"
+	case SourcePEX:
+		return "pex"
+	case SourceLSD:
+		return "lsd"
+	case SourceMagnet:
+		return "magnet"
+	}
+	return "unknown"
+}
+
+// Policy decides which peer sources and peers may be used for a torrent. For private
+// torrents (BEP 27) only the torrent's own trackers are trusted. It is safe for concurrent use.
+type Policy struct {
+	private bool
+
+	mu           sync.Mutex
+	trackerPeers map[string]struct{}
+}
+
+// NewPolicy creates a Policy for a torrent with the given private flag.
+func NewPolicy(private bool) *Policy {
+	return &Policy{private: private, trackerPeers: make(map[string]struct{})}
+}
+
+// Private tells if the policy enforces private torrent restrictions.
+func (p *Policy) Private() bool {
+	return p.private
+}
+
+// AllowsSource tells if peers may be discovered through the given source.
+func (p *Policy) AllowsSource(source Source) bool {
+	return !p.private || source == SourceTracker
+}
+
+// AddTrackerPeers records peers returned by one of the torrent's trackers.
+func (p *Policy) AddTrackerPeers(peers []ConnectionInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, peer := range peers {
+		p.trackerPeers[peer.String()] = struct{}{}
+	}
+}
+
+// AllowsConnection tells if an outgoing connection to the peer may be made. Private
+// torrents only connect to peers returned by their own trackers.
+func (p *Policy) AllowsConnection(peer ConnectionInfo) bool {
+	if !p.private {
+		return true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.trackerPeers[peer.String()]
+	return ok
+}
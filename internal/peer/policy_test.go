@@ -0,0 +1,42 @@
+package peer
+
+import (
+	"net"
+	"testing"
+)
+
+func TestPolicy(t *testing.T) {
+	trackerPeer := ConnectionInfo{IP: net.IPv4(192, 168, 0, 1), Port: 6881}
+	otherPeer := ConnectionInfo{IP: net.IPv4(192, 168, 0, 2), Port: 6881}
+	sources := []Source{SourceTracker, SourceDHT, SourcePEX, SourceLSD, SourceMagnet}
+
+	t.Run("Public", func(t *testing.T) {
+		policy := NewPolicy(false)
+
+		for _, source := range sources {
+			if !policy.AllowsSource(source) {
+				t.Errorf("Expected source %s to be allowed", source)
+			}
+		}
+		if !policy.AllowsConnection(otherPeer) {
+			t.Errorf("Expected connection to %s to be allowed", otherPeer)
+		}
+	})
+
+	t.Run("Private", func(t *testing.T) {
+		policy := NewPolicy(true)
+		policy.AddTrackerPeers([]ConnectionInfo{trackerPeer})
+
+		for _, source := range sources {
+			if got := policy.AllowsSource(source); got != (source == SourceTracker) {
+				t.Errorf("Expected AllowsSource(%s) to be %v, but got %v", source, source == SourceTracker, got)
+			}
+		}
+		if !policy.AllowsConnection(ConnectionInfo{IP: net.ParseIP("192.168.0.1"), Port: 6881}) {
+			t.Errorf("Expected connection to tracker peer %s to be allowed", trackerPeer)
+		}
+		if policy.AllowsConnection(otherPeer) {
+			t.Errorf("Expected connection to %s to be rejected", otherPeer)
+		}
+	})
+}
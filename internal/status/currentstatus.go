@@ -38,7 +38,7 @@ func (cs *CurrentStatus) Update(piece *message.Piece) error {
 }
 
 func (cs *CurrentStatus) readMessage() error {
-	msg, err := message.ReadPeerMessage(cs.Client.Conn)
+	msg, err := cs.Client.ReadMessage()
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package storage
+
+// setHidden does nothing outside Windows, where files are hidden by a leading dot in their name.
+func setHidden(path string) error {
+	return nil
+}
@@ -0,0 +1,281 @@
+// Package storage maps the pieces of a torrent onto the files of a download directory.
+package storage
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mattheworford/gotorrent/internal/torrentdata"
+)
+
+// Storage reads and writes the pieces of a torrent within a download directory. Padding
+// files are never created on disk; their contents are zeros. Symlinks and executable bits
+// are applied by Finalize once the download is complete. It is safe for concurrent use.
+type Storage struct {
+	dir     string
+	torrent *torrentdata.TorrentData
+
+	mu    sync.Mutex
+	files map[int]*os.File
+}
+
+// New returns a Storage placing the files of the torrent under dir. Every file path and
+// symlink target must stay within dir.
+func New(dir string, torrent *torrentdata.TorrentData) (*Storage, error) {
+	s := &Storage{dir: filepath.Clean(dir), torrent: torrent, files: make(map[int]*os.File)}
+	for i := range torrent.Files {
+		if _, err := s.localPath(torrent.Files[i].Path); err != nil {
+			return nil, err
+		}
+		if target := torrent.Files[i].SymlinkPath; target != nil {
+			if _, err := s.localPath(append(s.rootPath(), target...)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+// rootPath returns the path components of the torrent's root directory within dir.
+func (s *Storage) rootPath() []string {
+	if s.torrent.IsMultiFile() {
+		return []string{s.torrent.Name}
+	}
+	return nil
+}
+
+// localPath joins path components onto the download directory, rejecting any that escape it.
+func (s *Storage) localPath(components []string) (string, error) {
+	for _, component := range components {
+		if component == "" || component == "." || component == ".." || strings.ContainsAny(component, `/\`) {
+			return "", fmt.Errorf("storage: unsafe path %v", components)
+		}
+	}
+	return filepath.Join(append([]string{s.dir}, components...)...), nil
+}
+
+// Path returns the location on disk of the file at the given index.
+func (s *Storage) Path(fileIndex int) (string, error) {
+	if fileIndex < 0 || fileIndex >= len(s.torrent.Files) {
+		return "", fmt.Errorf("storage: file index %d out of range", fileIndex)
+	}
+	return s.localPath(s.torrent.Files[fileIndex].Path)
+}
+
+// file returns the open file at the given index, creating it and its directories if needed.
+func (s *Storage) file(fileIndex int) (*os.File, error) {
+	if f, ok := s.files[fileIndex]; ok {
+		return f, nil
+	}
+	path, err := s.Path(fileIndex)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("storage: failed to create directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("storage: failed to open file: %w", err)
+	}
+	s.files[fileIndex] = f
+	return f, nil
+}
+
+// stored tells if the file at the given index holds data on disk.
+func (s *Storage) stored(fileIndex int) bool {
+	f := &s.torrent.Files[fileIndex]
+	return !f.Padding && !f.IsSymlink()
+}
+
+// WritePiece writes a verified piece to the files it covers, skipping padding files.
+func (s *Storage) WritePiece(index int, data []byte) error {
+	size, err := s.torrent.PieceSize(index)
+	if err != nil {
+		return err
+	}
+	if len(data) != size {
+		return fmt.Errorf("storage: piece %d has %d bytes, expected %d", index, len(data), size)
+	}
+	spans, err := s.torrent.PieceSpans(index)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, span := range spans {
+		if !s.stored(span.FileIndex) {
+			continue
+		}
+		f, err := s.file(span.FileIndex)
+		if err != nil {
+			return err
+		}
+		if _, err := f.WriteAt(data[span.PieceOffset:span.PieceOffset+span.Length], int64(span.FileOffset)); err != nil {
+			return fmt.Errorf("storage: failed to write piece %d: %w", index, err)
+		}
+	}
+	return nil
+}
+
+// ReadPiece reads the piece at the given index, filling padding with zeros.
+func (s *Storage) ReadPiece(index int) ([]byte, error) {
+	size, err := s.torrent.PieceSize(index)
+	if err != nil {
+		return nil, err
+	}
+	spans, err := s.torrent.PieceSpans(index)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	piece := make([]byte, size)
+	for _, span := range spans {
+		if !s.stored(span.FileIndex) {
+			continue
+		}
+		f, err := s.file(span.FileIndex)
+		if err != nil {
+			return nil, err
+		}
+		buf := piece[span.PieceOffset : span.PieceOffset+span.Length]
+		if _, err := f.ReadAt(buf, int64(span.FileOffset)); err != nil {
+			return nil, fmt.Errorf("storage: failed to read piece %d: %w", index, err)
+		}
+	}
+	return piece, nil
+}
+
+// VerifyFile checks a file on disk against its BEP 47 sha1 hash. It fails if the torrent
+// carries no hash for the file.
+func (s *Storage) VerifyFile(fileIndex int) (bool, error) {
+	path, err := s.Path(fileIndex)
+	if err != nil {
+		return false, err
+	}
+	f := s.torrent.Files[fileIndex]
+	if f.SHA1 == [20]byte{} {
+		return false, fmt.Errorf("storage: file %v has no sha1 hash", f.Path)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("storage: failed to open file: %w", err)
+	}
+	defer file.Close()
+	h := sha1.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return false, fmt.Errorf("storage: failed to read file: %w", err)
+	}
+	return bytes.Equal(h.Sum(nil), f.SHA1[:]), nil
+}
+
+// Finalize completes a finished download: it closes the open files, creates empty files
+// that no piece covered, marks executable files as such, sets hidden files' attributes
+// where the platform has them, and creates symlinks relative to their own directory.
+func (s *Storage) Finalize() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.closeFiles(); err != nil {
+		return err
+	}
+
+	for i := range s.torrent.Files {
+		f := &s.torrent.Files[i]
+		if f.Padding {
+			continue
+		}
+		path, err := s.localPath(f.Path)
+		if err != nil {
+			return err
+		}
+		if f.IsSymlink() {
+			if err := s.createSymlink(path, f.SymlinkPath); err != nil {
+				return err
+			}
+			continue
+		}
+		if f.Length == 0 {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return fmt.Errorf("storage: failed to create directory: %w", err)
+			}
+			file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0o644)
+			if err != nil {
+				return fmt.Errorf("storage: failed to create file: %w", err)
+			}
+			file.Close()
+		}
+		if f.Executable {
+			info, err := os.Stat(path)
+			if err != nil {
+				return fmt.Errorf("storage: failed to stat file: %w", err)
+			}
+			// Grant execute permission to whoever may read the file.
+			mode := info.Mode().Perm()
+			if err := os.Chmod(path, mode|(mode&0o444)>>2); err != nil {
+				return fmt.Errorf("storage: failed to mark file executable: %w", err)
+			}
+		}
+		if f.Hidden {
+			if err := setHidden(path); err != nil {
+				return fmt.Errorf("storage: failed to hide file: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// createSymlink replaces whatever is at path with a relative symlink to the root-relative target.
+func (s *Storage) createSymlink(path string, target []string) error {
+	targetPath, err := s.localPath(append(s.rootPath(), target...))
+	if err != nil {
+		return err
+	}
+	relative, err := filepath.Rel(filepath.Dir(path), targetPath)
+	if err != nil {
+		return fmt.Errorf("storage: failed to resolve symlink target: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("storage: failed to create directory: %w", err)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("storage: failed to replace symlink: %w", err)
+	}
+	if err := os.Symlink(relative, path); err != nil {
+		return fmt.Errorf("storage: failed to create symlink: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) closeFiles() error {
+	var errs []error
+	for index, f := range s.files {
+		if err := f.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(s.files, index)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("storage: failed to close files: %w", err)
+	}
+	return nil
+}
+
+// Close closes the files opened by the storage.
+func (s *Storage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeFiles()
+}
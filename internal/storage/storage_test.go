@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mattheworford/gotorrent/internal/torrentdata"
+)
+
+const pieceLength = 32
+
+// paddedTorrent returns a torrent whose data is an executable script, padding, a hidden
+// file and a symlink to the script, along with the concatenated piece data.
+func paddedTorrent() (*torrentdata.TorrentData, []byte) {
+	script := []byte(strings.Repeat("s", 20))
+	hidden := []byte(strings.Repeat("h", 40))
+	data := append(append(append([]byte{}, script...), make([]byte, 12)...), hidden...)
+
+	var hashes [][20]byte
+	for begin := 0; begin < len(data); begin += pieceLength {
+		hashes = append(hashes, sha1.Sum(data[begin:min(begin+pieceLength, len(data))]))
+	}
+	torrent := &torrentdata.TorrentData{
+		Name:        "root",
+		PieceHashes: hashes,
+		PieceLength: pieceLength,
+		Length:      len(data),
+		Files: []torrentdata.File{
+			{Path: []string{"root", "bin", "run.sh"}, Length: 20, Executable: true, SHA1: sha1.Sum(script)},
+			{Path: []string{"root", ".pad", "12"}, Length: 12, Offset: 20, Padding: true},
+			{Path: []string{"root", ".hidden"}, Length: 40, Offset: 32, Hidden: true},
+			{Path: []string{"root", "empty"}, Offset: 72},
+			{Path: []string{"root", "links", "run"}, Offset: 72, SymlinkPath: []string{"bin", "run.sh"}},
+		},
+	}
+	return torrent, data
+}
+
+func TestStorage(t *testing.T) {
+	torrent, data := paddedTorrent()
+	dir := t.TempDir()
+	s, err := New(dir, torrent)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer s.Close()
+
+	for index := range torrent.PieceHashes {
+		piece := data[index*pieceLength : min((index+1)*pieceLength, len(data))]
+		if err := s.WritePiece(index, piece); err != nil {
+			t.Fatalf("WritePiece(%d) failed: %v", index, err)
+		}
+	}
+
+	t.Run("SkipsPadding", func(t *testing.T) {
+		if _, err := os.Stat(filepath.Join(dir, "root", ".pad")); !os.IsNotExist(err) {
+			t.Errorf("Expected no padding directory, got %v", err)
+		}
+		got, err := os.ReadFile(filepath.Join(dir, "root", ".hidden"))
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if !bytes.Equal(got, data[32:]) {
+			t.Errorf("Unexpected file contents: got %q, want %q", got, data[32:])
+		}
+	})
+
+	t.Run("ReadPiece", func(t *testing.T) {
+		piece, err := s.ReadPiece(0)
+		if err != nil {
+			t.Fatalf("ReadPiece failed: %v", err)
+		}
+		if !bytes.Equal(piece, data[:pieceLength]) {
+			t.Errorf("Unexpected piece: got %q, want %q", piece, data[:pieceLength])
+		}
+	})
+
+	t.Run("Finalize", func(t *testing.T) {
+		if err := s.Finalize(); err != nil {
+			t.Fatalf("Finalize failed: %v", err)
+		}
+		info, err := os.Stat(filepath.Join(dir, "root", "bin", "run.sh"))
+		if err != nil {
+			t.Fatalf("Failed to stat file: %v", err)
+		}
+		if info.Mode().Perm()&0o100 == 0 {
+			t.Errorf("Expected executable file, got mode %v", info.Mode())
+		}
+		if _, err := os.Stat(filepath.Join(dir, "root", "empty")); err != nil {
+			t.Errorf("Expected empty file to be created: %v", err)
+		}
+		target, err := os.Readlink(filepath.Join(dir, "root", "links", "run"))
+		if err != nil {
+			t.Fatalf("Failed to read symlink: %v", err)
+		}
+		if expected := filepath.Join("..", "bin", "run.sh"); target != expected {
+			t.Errorf("Unexpected symlink target: got %q, want %q", target, expected)
+		}
+	})
+
+	t.Run("VerifyFile", func(t *testing.T) {
+		ok, err := s.VerifyFile(0)
+		if err != nil {
+			t.Fatalf("VerifyFile failed: %v", err)
+		}
+		if !ok {
+			t.Error("Expected file to match its sha1 hash")
+		}
+		if _, err := s.VerifyFile(2); err == nil {
+			t.Error("Expected error for a file without sha1, got nil")
+		}
+	})
+}
+
+func TestNewRejectsUnsafePaths(t *testing.T) {
+	testCases := []struct {
+		name string
+		file torrentdata.File
+	}{
+		{"Traversal", torrentdata.File{Path: []string{"root", "..", "..", "etc", "passwd"}}},
+		{"Separator", torrentdata.File{Path: []string{"root", "a/../../b"}}},
+		{"SymlinkTraversal", torrentdata.File{Path: []string{"root", "link"}, SymlinkPath: []string{"..", "..", "etc"}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			torrent := &torrentdata.TorrentData{Name: "root", Files: []torrentdata.File{{Path: []string{"root", "a"}}, tc.file}}
+			if _, err := New(t.TempDir(), torrent); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
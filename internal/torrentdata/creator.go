@@ -0,0 +1,254 @@
+package torrentdata
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/bencode"
+)
+
+const (
+	minPieceLength   = 16 * 1024
+	maxPieceLength   = 16 * 1024 * 1024
+	targetPieceCount = 2000
+)
+
+// CreateOptions configures the torrent built by Create.
+type CreateOptions struct {
+	// PieceLength overrides the automatically chosen piece length; it must be a power of two.
+	PieceLength int
+	// AnnounceList holds the tracker tiers; the first tracker also becomes the announce URL.
+	AnnounceList [][]string
+	Comment      string
+	CreatedBy    string
+	// CreationDate is omitted from the torrent when zero.
+	CreationDate time.Time
+	Private      bool
+	Source       string
+	WebSeeds     []string
+	// Workers bounds the number of pieces hashed in parallel, defaulting to the number of CPUs.
+	Workers int
+}
+
+// sourceFile represents a file on disk that is part of a torrent being created.
+type sourceFile struct {
+	diskPath string
+	path     []string
+	length   int
+}
+
+// Create builds the metainfo of a torrent containing the file or directory at root.
+func Create(root string, opts CreateOptions) (*MetainfoFile, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("torrentdata: failed to resolve %s: %w", root, err)
+	}
+	name := filepath.Base(abs)
+	if !validPathComponent(name) {
+		return nil, fmt.Errorf("torrentdata: cannot name a torrent after %s", root)
+	}
+	stat, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("torrentdata: failed to stat %s: %w", root, err)
+	}
+	files, err := collectFiles(root, stat)
+	if err != nil {
+		return nil, err
+	}
+	total := 0
+	for _, f := range files {
+		total += f.length
+	}
+
+	pieceLength := opts.PieceLength
+	if pieceLength == 0 {
+		pieceLength = choosePieceLength(total)
+	}
+	if pieceLength <= 0 || pieceLength&(pieceLength-1) != 0 {
+		return nil, fmt.Errorf("torrentdata: piece length %d is not a power of two", pieceLength)
+	}
+
+	pieces, err := hashPieces(files, total, pieceLength, opts.Workers)
+	if err != nil {
+		return nil, err
+	}
+
+	info := InfoDictionary{
+		Pieces:      string(pieces),
+		PieceLength: pieceLength,
+		Name:        name,
+		Source:      opts.Source,
+	}
+	if opts.Private {
+		info.Private = 1
+	}
+	if stat.IsDir() {
+		info.Files = make([]FileDictionary, len(files))
+		for i, f := range files {
+			info.Files[i] = FileDictionary{Length: f.length, Path: f.path}
+		}
+	} else {
+		info.Length = total
+	}
+
+	rawInfo, err := bencode.Marshal(info)
+	if err != nil {
+		return nil, fmt.Errorf("torrentdata: failed to marshal InfoDictionary: %w", err)
+	}
+
+	metainfoFile := &MetainfoFile{
+		Comment:   opts.Comment,
+		CreatedBy: opts.CreatedBy,
+		URLList:   opts.WebSeeds,
+		Info:      info,
+		rawInfo:   rawInfo,
+	}
+	if !opts.CreationDate.IsZero() {
+		metainfoFile.CreationDate = opts.CreationDate.Unix()
+	}
+	metainfoFile.SetTrackers(opts.AnnounceList)
+	return metainfoFile, nil
+}
+
+// collectFiles lists the regular files making up a torrent, in lexical order.
+func collectFiles(root string, stat fs.FileInfo) ([]sourceFile, error) {
+	if !stat.IsDir() {
+		if !stat.Mode().IsRegular() {
+			return nil, fmt.Errorf("torrentdata: %s is not a regular file", root)
+		}
+		return []sourceFile{{diskPath: root, path: []string{stat.Name()}, length: int(stat.Size())}}, nil
+	}
+
+	var files []sourceFile
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, sourceFile{
+			diskPath: path,
+			path:     strings.Split(filepath.ToSlash(rel), "/"),
+			length:   int(info.Size()),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("torrentdata: failed to walk %s: %w", root, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("torrentdata: %s contains no files", root)
+	}
+	return files, nil
+}
+
+// choosePieceLength picks a power-of-two piece length that keeps the piece count near targetPieceCount.
+func choosePieceLength(total int) int {
+	length := minPieceLength
+	for length < maxPieceLength && (total+length-1)/length > targetPieceCount {
+		length *= 2
+	}
+	return length
+}
+
+// hashPieces computes the concatenated SHA-1 hashes of every piece, hashing pieces in parallel.
+func hashPieces(files []sourceFile, total, pieceLength, workers int) ([]byte, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	numPieces := (total + pieceLength - 1) / pieceLength
+	hashes := make([]byte, numPieces*sha1.Size)
+
+	layout := TorrentData{PieceHashes: make([][20]byte, numPieces), PieceLength: pieceLength, Length: total}
+	layout.Files = make([]File, len(files))
+	offset := 0
+	for i, f := range files {
+		layout.Files[i] = File{Path: f.path, Length: f.length, Offset: offset}
+		offset += f.length
+	}
+
+	indices := make(chan int)
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, pieceLength)
+			for index := range indices {
+				piece, err := readPiece(&layout, files, index, buf)
+				if err != nil {
+					errs <- err
+					// Drain the remaining indices so the producer does not block.
+					for range indices {
+					}
+					return
+				}
+				h := sha1.Sum(piece)
+				copy(hashes[index*sha1.Size:], h[:])
+			}
+		}()
+	}
+	for index := 0; index < numPieces; index++ {
+		indices <- index
+	}
+	close(indices)
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// readPiece reads the piece at the given index from the source files into buf.
+func readPiece(layout *TorrentData, files []sourceFile, index int, buf []byte) ([]byte, error) {
+	size, err := layout.PieceSize(index)
+	if err != nil {
+		return nil, err
+	}
+	spans, err := layout.PieceSpans(index)
+	if err != nil {
+		return nil, err
+	}
+	for _, span := range spans {
+		if err := readSpan(files[span.FileIndex].diskPath, buf[span.PieceOffset:span.PieceOffset+span.Length], span.FileOffset); err != nil {
+			return nil, err
+		}
+	}
+	return buf[:size], nil
+}
+
+func readSpan(path string, buf []byte, offset int) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("torrentdata: failed to open file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.ReadAt(buf, int64(offset)); err != nil {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("torrentdata: %s changed while hashing", path)
+		}
+		return fmt.Errorf("torrentdata: failed to read %s: %w", path, err)
+	}
+	return nil
+}
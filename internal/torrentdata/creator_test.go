@@ -0,0 +1,196 @@
+package torrentdata
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeTestFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+}
+
+func expectedPieces(data []byte, pieceLength int) string {
+	var pieces []byte
+	for begin := 0; begin < len(data); begin += pieceLength {
+		end := min(begin+pieceLength, len(data))
+		h := sha1.Sum(data[begin:end])
+		pieces = append(pieces, h[:]...)
+	}
+	return string(pieces)
+}
+
+func TestCreate(t *testing.T) {
+	t.Run("Directory", func(t *testing.T) {
+		root := filepath.Join(t.TempDir(), "release")
+		a := bytes.Repeat([]byte("a"), 20000)
+		b := bytes.Repeat([]byte("b"), 30000)
+		writeTestFile(t, filepath.Join(root, "a.bin"), a)
+		writeTestFile(t, filepath.Join(root, "docs", "b.txt"), b)
+		writeTestFile(t, filepath.Join(root, "empty"), nil)
+
+		metainfoFile, err := Create(root, CreateOptions{
+			PieceLength:  minPieceLength,
+			AnnounceList: [][]string{{"http://a/announce", "http://b/announce"}, {"udp://c:1337"}},
+			Comment:      "nightly build",
+			CreatedBy:    "gotorrent",
+			CreationDate: time.Unix(1700000000, 0),
+			Private:      true,
+			Source:       "CI",
+			WebSeeds:     []string{"http://mirror.example/"},
+			Workers:      3,
+		})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+
+		var buf bytes.Buffer
+		if err := metainfoFile.Write(&buf); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		path := filepath.Join(t.TempDir(), "release.torrent")
+		writeTestFile(t, path, buf.Bytes())
+
+		opened, err := Open(path)
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		if !bytes.Equal(opened.rawInfo, metainfoFile.rawInfo) {
+			t.Errorf("Written info dictionary differs from the created one")
+		}
+
+		expected := MetainfoFile{
+			Announce:     "http://a/announce",
+			AnnounceList: [][]string{{"http://a/announce", "http://b/announce"}, {"udp://c:1337"}},
+			Comment:      "nightly build",
+			CreatedBy:    "gotorrent",
+			CreationDate: 1700000000,
+			URLList:      []string{"http://mirror.example/"},
+			Info: InfoDictionary{
+				Pieces:      expectedPieces(append(append([]byte{}, a...), b...), minPieceLength),
+				PieceLength: minPieceLength,
+				Name:        "release",
+				Files: []FileDictionary{
+					{Length: 20000, Path: []string{"a.bin"}},
+					{Length: 30000, Path: []string{"docs", "b.txt"}},
+					{Length: 0, Path: []string{"empty"}},
+				},
+				Private: 1,
+				Source:  "CI",
+			},
+		}
+		opened.rawInfo = nil
+		if !reflect.DeepEqual(*opened, expected) {
+			t.Errorf("Unexpected metainfo. Expected: %+v, Got: %+v", expected, *opened)
+		}
+	})
+
+	t.Run("SingleFileWithAutomaticPieceLength", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "image.iso")
+		data := bytes.Repeat([]byte{0xAB}, 70000)
+		writeTestFile(t, path, data)
+
+		metainfoFile, err := Create(path, CreateOptions{AnnounceList: [][]string{{"http://a/announce"}}})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		torrentData, err := metainfoFile.toTorrentData()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if torrentData.PieceLength != minPieceLength {
+			t.Errorf("Unexpected PieceLength value. Expected: %d, Got: %d", minPieceLength, torrentData.PieceLength)
+		}
+		if metainfoFile.Info.Pieces != expectedPieces(data, minPieceLength) {
+			t.Error("Unexpected piece hashes")
+		}
+		if torrentData.Length != len(data) || torrentData.Name != "image.iso" || torrentData.IsMultiFile() {
+			t.Errorf("Unexpected layout: %+v", torrentData)
+		}
+		if metainfoFile.AnnounceList != nil {
+			t.Errorf("Unexpected AnnounceList for a single tracker: %v", metainfoFile.AnnounceList)
+		}
+		if torrentData.InfoHash != sha1.Sum(metainfoFile.rawInfo) {
+			t.Error("InfoHash does not match the encoded info dictionary")
+		}
+	})
+
+	t.Run("InvalidPieceLength", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "file")
+		writeTestFile(t, path, []byte("data"))
+
+		_, err := Create(path, CreateOptions{PieceLength: 3000})
+		if err == nil {
+			t.Error("Expected error, got nil")
+		} else if err.Error() != "torrentdata: piece length 3000 is not a power of two" {
+			t.Errorf("Unexpected error message: got %q, want %q", err.Error(), "torrentdata: piece length 3000 is not a power of two")
+		}
+	})
+
+	t.Run("CurrentDirectory", func(t *testing.T) {
+		root := filepath.Join(t.TempDir(), "release")
+		writeTestFile(t, filepath.Join(root, "a.bin"), []byte("data"))
+		wd, err := os.Getwd()
+		if err != nil {
+			t.Fatalf("Getwd failed: %v", err)
+		}
+		if err := os.Chdir(root); err != nil {
+			t.Fatalf("Chdir failed: %v", err)
+		}
+		defer os.Chdir(wd)
+
+		metainfoFile, err := Create(".", CreateOptions{})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if metainfoFile.Info.Name != "release" {
+			t.Errorf("Unexpected Name: got %q, want %q", metainfoFile.Info.Name, "release")
+		}
+	})
+
+	t.Run("RootDirectory", func(t *testing.T) {
+		_, err := Create(string(filepath.Separator), CreateOptions{})
+		if err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+
+	t.Run("EmptyDirectory", func(t *testing.T) {
+		root := t.TempDir()
+
+		_, err := Create(root, CreateOptions{})
+		if err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+}
+
+func TestChoosePieceLength(t *testing.T) {
+	testCases := []struct {
+		total    int
+		expected int
+	}{
+		{0, minPieceLength},
+		{targetPieceCount * minPieceLength, minPieceLength},
+		{targetPieceCount*minPieceLength + 1, 2 * minPieceLength},
+		{670040064, 512 * 1024},
+		{1 << 50, maxPieceLength},
+	}
+
+	for _, tc := range testCases {
+		if got := choosePieceLength(tc.total); got != tc.expected {
+			t.Errorf("Expected choosePieceLength(%d) to be %d, but got %d", tc.total, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package torrentdata
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/mattheworford/gotorrent/internal/bencode"
+)
+
+// Trackers returns the tracker tiers of the metainfo file.
+func (metainfoFile *MetainfoFile) Trackers() [][]string {
+	return metainfoFile.announceTiers()
+}
+
+// SetTrackers replaces the tracker tiers. The first tracker becomes the announce URL, and
+// the announce-list is only kept when there is more than one tracker.
+func (metainfoFile *MetainfoFile) SetTrackers(tiers [][]string) {
+	tiers = cleanTiers(tiers)
+	metainfoFile.Announce = ""
+	metainfoFile.AnnounceList = nil
+	if len(tiers) > 0 {
+		metainfoFile.Announce = tiers[0][0]
+	}
+	if len(tiers) > 1 || (len(tiers) == 1 && len(tiers[0]) > 1) {
+		metainfoFile.AnnounceList = tiers
+	}
+}
+
+// ReplaceTracker swaps every occurrence of the tracker URL oldURL for newURL, keeping its
+// place in the tiers, and tells if oldURL was found. An empty newURL removes the tracker.
+func (metainfoFile *MetainfoFile) ReplaceTracker(oldURL, newURL string) bool {
+	tiers := metainfoFile.Trackers()
+	found := false
+	for _, tier := range tiers {
+		for i, tracker := range tier {
+			if tracker == oldURL {
+				tier[i] = newURL
+				found = true
+			}
+		}
+	}
+	if found {
+		metainfoFile.SetTrackers(tiers)
+	}
+	return found
+}
+
+// Write encodes the metainfo file as bencode. A decoded or created info dictionary is
+// written with its original bytes, so the info hash never changes and edits to Info are
+// ignored; top-level keys without a MetainfoFile field are carried over unchanged.
+func (metainfoFile *MetainfoFile) Write(w io.Writer) error {
+	encoded, err := bencode.Marshal(*metainfoFile)
+	if err != nil {
+		return fmt.Errorf("torrentdata: failed to marshal MetainfoFile: %w", err)
+	}
+	var metainfo map[string]bencode.RawMessage
+	if err := bencode.Unmarshal(encoded, &metainfo); err != nil {
+		return fmt.Errorf("torrentdata: failed to marshal MetainfoFile: %w", err)
+	}
+	for key, value := range metainfoFile.extra {
+		metainfo[key] = value
+	}
+	if metainfoFile.rawInfo != nil {
+		metainfo["info"] = metainfoFile.rawInfo
+	}
+	if err := bencode.NewEncoder(w).Encode(metainfo); err != nil {
+		return fmt.Errorf("torrentdata: failed to marshal MetainfoFile: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package torrentdata
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEditMetainfo(t *testing.T) {
+	// The info dictionary is not canonical (unsorted keys), so re-encoding it would change the hash.
+	info := "d4:name1:a6:lengthi1e12:piece lengthi16384e6:pieces20:abcdefghijklmnopqrst7:privatei1ee"
+	data := "d8:announce8:http://a7:comment3:old4:info" + info + "5:nodesll9:127.0.0.1i6881eeee"
+	path := filepath.Join(t.TempDir(), "edit.torrent")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("Failed to write torrent: %v", err)
+	}
+
+	metainfoFile, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	metainfoFile.SetTrackers([][]string{{"http://b", "http://c"}, {"udp://d:1337"}})
+	metainfoFile.Comment = "new"
+	metainfoFile.CreatedBy = "gotorrent"
+	metainfoFile.URLList = URLList{"http://mirror/"}
+
+	var buf bytes.Buffer
+	if err := metainfoFile.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	expected := "d8:announce8:http://b13:announce-listll8:http://b8:http://cel12:udp://d:1337ee" +
+		"7:comment3:new10:created by9:gotorrent4:info" + info + "5:nodesll9:127.0.0.1i6881eee" +
+		"8:url-listl14:http://mirror/ee"
+	if buf.String() != expected {
+		t.Errorf("Unexpected encoding:\ngot  %q\nwant %q", buf.String(), expected)
+	}
+
+	torrent, err := ParseBytes(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ParseBytes failed: %v", err)
+	}
+	if torrent.InfoHash != sha1.Sum([]byte(info)) {
+		t.Errorf("Info hash changed: got %x, want %x", torrent.InfoHash, sha1.Sum([]byte(info)))
+	}
+}
+
+func TestReplaceTracker(t *testing.T) {
+	testCases := []struct {
+		name     string
+		metainfo MetainfoFile
+		old, new string
+		found    bool
+		expected [][]string
+	}{
+		{
+			name:     "AnnounceOnly",
+			metainfo: MetainfoFile{Announce: "http://a"},
+			old:      "http://a",
+			new:      "http://b",
+			found:    true,
+			expected: [][]string{{"http://b"}},
+		},
+		{
+			name:     "KeepsPosition",
+			metainfo: MetainfoFile{Announce: "http://a", AnnounceList: [][]string{{"http://a", "http://b"}, {"http://c"}}},
+			old:      "http://b",
+			new:      "http://x",
+			found:    true,
+			expected: [][]string{{"http://a", "http://x"}, {"http://c"}},
+		},
+		{
+			name:     "Remove",
+			metainfo: MetainfoFile{Announce: "http://a", AnnounceList: [][]string{{"http://a"}, {"http://c"}}},
+			old:      "http://a",
+			new:      "",
+			found:    true,
+			expected: [][]string{{"http://c"}},
+		},
+		{
+			name:     "NotFound",
+			metainfo: MetainfoFile{Announce: "http://a"},
+			old:      "http://z",
+			new:      "http://b",
+			found:    false,
+			expected: [][]string{{"http://a"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if found := tc.metainfo.ReplaceTracker(tc.old, tc.new); found != tc.found {
+				t.Errorf("Unexpected result: got %v, want %v", found, tc.found)
+			}
+			if got := tc.metainfo.Trackers(); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Unexpected trackers: got %v, want %v", got, tc.expected)
+			}
+			if tc.metainfo.Announce != tc.expected[0][0] {
+				t.Errorf("Unexpected Announce: got %q, want %q", tc.metainfo.Announce, tc.expected[0][0])
+			}
+		})
+	}
+}
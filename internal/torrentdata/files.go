@@ -0,0 +1,197 @@
+package torrentdata
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FileDictionary represents a single entry of the files list in a multi-file torrent.
+type FileDictionary struct {
+	Length int      `bencode:"length"`
+	Path   []string `bencode:"path"`
+	// Attr, SHA1 and SymlinkPath are the optional BEP 47 file attributes.
+	Attr        string   `bencode:"attr,omitempty"`
+	SHA1        string   `bencode:"sha1,omitempty"`
+	SymlinkPath []string `bencode:"symlink path,omitempty"`
+}
+
+// File represents a file described by a torrent, located within the torrent's data.
+type File struct {
+	// Path holds the path components relative to the download directory,
+	// starting with the root directory for multi-file torrents.
+	Path   []string
+	Length int
+	Offset int
+	// PiecesRoot is the merkle root of the file in v2 torrents.
+	PiecesRoot [32]byte
+	// Padding marks a BEP 47 padding file, which only aligns the next file to a piece boundary.
+	Padding    bool
+	Executable bool
+	Hidden     bool
+	// SymlinkPath holds the target path components of a symlink, relative to the root
+	// directory of the torrent; it is nil for regular files.
+	SymlinkPath []string
+	// SHA1 is the optional hash of the file's contents, or zero when absent.
+	SHA1 [20]byte
+}
+
+// IsSymlink tells if the file is a symlink rather than a regular file.
+func (f *File) IsSymlink() bool {
+	return f.SymlinkPath != nil
+}
+
+// setAttributes applies the BEP 47 attributes of a file entry. Unknown attributes are ignored.
+func (f *File) setAttributes(attr, sha1Hash string, symlinkPath []string) error {
+	symlink := false
+	for _, c := range attr {
+		switch c {
+		case 'p':
+			f.Padding = true
+		case 'x':
+			f.Executable = true
+		case 'h':
+			f.Hidden = true
+		case 'l':
+			symlink = true
+		}
+	}
+	if symlink {
+		if len(symlinkPath) == 0 {
+			return fmt.Errorf("torrentdata: symlink %v has no target", f.Path)
+		}
+		for _, component := range symlinkPath {
+			if !validPathComponent(component) {
+				return fmt.Errorf("torrentdata: symlink %v has invalid target %v", f.Path, symlinkPath)
+			}
+		}
+		f.SymlinkPath = append([]string(nil), symlinkPath...)
+	}
+	if sha1Hash != "" {
+		if len(sha1Hash) != len(f.SHA1) {
+			return fmt.Errorf("torrentdata: file %v has malformed sha1", f.Path)
+		}
+		copy(f.SHA1[:], sha1Hash)
+	}
+	return nil
+}
+
+// validPathComponent tells if a path component names an entry of its directory, rather
+// than being empty or able to escape it.
+func validPathComponent(component string) bool {
+	return component != "" && component != "." && component != ".." && !strings.ContainsAny(component, "/\\")
+}
+
+// FileSpan represents the part of a piece that belongs to a single file.
+type FileSpan struct {
+	FileIndex   int
+	FileOffset  int
+	PieceOffset int
+	Length      int
+}
+
+// isMultiFile tells if the info dictionary uses the multi-file layout.
+func (i *InfoDictionary) isMultiFile() bool {
+	return len(i.Files) > 0
+}
+
+// buildFiles lays out the files described by the info dictionary and returns them with the total length.
+func (i *InfoDictionary) buildFiles() ([]File, int, error) {
+	if !validPathComponent(i.Name) {
+		return nil, 0, fmt.Errorf("torrentdata: invalid name %q", i.Name)
+	}
+	if !i.isMultiFile() {
+		if i.Length < 0 {
+			return nil, 0, errors.New("torrentdata: negative length")
+		}
+		f := File{Path: []string{i.Name}, Length: i.Length}
+		if err := f.setAttributes(i.Attr, i.SHA1, i.SymlinkPath); err != nil {
+			return nil, 0, err
+		}
+		return []File{f}, i.Length, nil
+	}
+	if i.Length != 0 {
+		return nil, 0, errors.New("torrentdata: both length and files are present")
+	}
+
+	files := make([]File, len(i.Files))
+	offset := 0
+	for index, f := range i.Files {
+		if f.Length < 0 {
+			return nil, 0, fmt.Errorf("torrentdata: file %d has negative length", index)
+		}
+		if len(f.Path) == 0 {
+			return nil, 0, fmt.Errorf("torrentdata: file %d has empty path", index)
+		}
+		for _, component := range f.Path {
+			if !validPathComponent(component) {
+				return nil, 0, fmt.Errorf("torrentdata: file %d has invalid path component %q", index, component)
+			}
+		}
+		path := make([]string, 0, len(f.Path)+1)
+		path = append(path, i.Name)
+		path = append(path, f.Path...)
+		files[index] = File{Path: path, Length: f.Length, Offset: offset}
+		if err := files[index].setAttributes(f.Attr, f.SHA1, f.SymlinkPath); err != nil {
+			return nil, 0, err
+		}
+		offset += f.Length
+	}
+	return files, offset, nil
+}
+
+// IsMultiFile tells if the torrent uses the multi-file layout, in which case Name is the root directory.
+func (t *TorrentData) IsMultiFile() bool {
+	return len(t.Files) > 0 && len(t.Files[0].Path) > 1
+}
+
+// PieceSize returns the size of the piece at the given index, accounting for a shorter last piece.
+func (t *TorrentData) PieceSize(index int) (int, error) {
+	if index < 0 || index >= len(t.PieceHashes) {
+		return 0, fmt.Errorf("torrentdata: piece index %d out of range", index)
+	}
+	begin := index * t.PieceLength
+	end := begin + t.PieceLength
+	if end > t.Length {
+		end = t.Length
+	}
+	if end < begin {
+		return 0, nil
+	}
+	return end - begin, nil
+}
+
+// PieceSpans maps the piece at the given index onto the files it covers, in order. Spans of
+// padding files are included so that the spans cover the whole piece; their contents are
+// zeros that are never stored on disk or served by web seeds.
+func (t *TorrentData) PieceSpans(index int) ([]FileSpan, error) {
+	size, err := t.PieceSize(index)
+	if err != nil {
+		return nil, err
+	}
+	begin := index * t.PieceLength
+	end := begin + size
+
+	// Find the first file that ends after the start of the piece.
+	first := sort.Search(len(t.Files), func(i int) bool {
+		return t.Files[i].Offset+t.Files[i].Length > begin
+	})
+
+	var spans []FileSpan
+	for i := first; i < len(t.Files) && t.Files[i].Offset < end; i++ {
+		f := t.Files[i]
+		spanBegin := max(begin, f.Offset)
+		spanEnd := min(end, f.Offset+f.Length)
+		if spanEnd <= spanBegin {
+			continue
+		}
+		spans = append(spans, FileSpan{
+			FileIndex:   i,
+			FileOffset:  spanBegin - f.Offset,
+			PieceOffset: spanBegin - begin,
+			Length:      spanEnd - spanBegin,
+		})
+	}
+	return spans, nil
+}
@@ -0,0 +1,225 @@
+package torrentdata
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildFiles(t *testing.T) {
+	t.Run("SingleFile", func(t *testing.T) {
+		info := InfoDictionary{Length: 1024, Name: "example.iso"}
+
+		files, length, err := info.buildFiles()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := []File{{Path: []string{"example.iso"}, Length: 1024}}
+		if !reflect.DeepEqual(files, expected) {
+			t.Errorf("Unexpected files. Expected: %v, Got: %v", expected, files)
+		}
+		if length != 1024 {
+			t.Errorf("Unexpected length. Expected: %d, Got: %d", 1024, length)
+		}
+	})
+
+	t.Run("MultiFile", func(t *testing.T) {
+		info := InfoDictionary{
+			Name: "root",
+			Files: []FileDictionary{
+				{Length: 100, Path: []string{"a.txt"}},
+				{Length: 250, Path: []string{"sub", "b.txt"}},
+			},
+		}
+
+		files, length, err := info.buildFiles()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := []File{
+			{Path: []string{"root", "a.txt"}, Length: 100, Offset: 0},
+			{Path: []string{"root", "sub", "b.txt"}, Length: 250, Offset: 100},
+		}
+		if !reflect.DeepEqual(files, expected) {
+			t.Errorf("Unexpected files. Expected: %v, Got: %v", expected, files)
+		}
+		if length != 350 {
+			t.Errorf("Unexpected length. Expected: %d, Got: %d", 350, length)
+		}
+	})
+
+	t.Run("Attributes", func(t *testing.T) {
+		hash := "0123456789abcdefghij"
+		info := InfoDictionary{
+			Name: "root",
+			Files: []FileDictionary{
+				{Length: 20, Path: []string{"run.sh"}, Attr: "x", SHA1: hash},
+				{Length: 12, Path: []string{".pad", "12"}, Attr: "p"},
+				{Length: 0, Path: []string{"link"}, Attr: "l", SymlinkPath: []string{"run.sh"}},
+				{Length: 5, Path: []string{".hidden"}, Attr: "hz"},
+			},
+		}
+
+		files, _, err := info.buildFiles()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := []File{
+			{Path: []string{"root", "run.sh"}, Length: 20, Executable: true},
+			{Path: []string{"root", ".pad", "12"}, Length: 12, Offset: 20, Padding: true},
+			{Path: []string{"root", "link"}, Offset: 32, SymlinkPath: []string{"run.sh"}},
+			{Path: []string{"root", ".hidden"}, Length: 5, Offset: 32, Hidden: true},
+		}
+		copy(expected[0].SHA1[:], hash)
+		if !reflect.DeepEqual(files, expected) {
+			t.Errorf("Unexpected files. Expected: %+v, Got: %+v", expected, files)
+		}
+		if !files[2].IsSymlink() || files[0].IsSymlink() {
+			t.Errorf("Unexpected symlink detection: %v, %v", files[2].IsSymlink(), files[0].IsSymlink())
+		}
+	})
+
+	testCases := []struct {
+		name       string
+		info       InfoDictionary
+		errMessage string
+	}{
+		{
+			name:       "SymlinkWithoutTarget",
+			info:       InfoDictionary{Name: "root", Files: []FileDictionary{{Path: []string{"link"}, Attr: "l"}}},
+			errMessage: "torrentdata: symlink [root link] has no target",
+		},
+		{
+			name:       "SymlinkTraversal",
+			info:       InfoDictionary{Name: "root", Files: []FileDictionary{{Path: []string{"link"}, Attr: "l", SymlinkPath: []string{"..", "etc"}}}},
+			errMessage: "torrentdata: symlink [root link] has invalid target [.. etc]",
+		},
+		{
+			name:       "MalformedSHA1",
+			info:       InfoDictionary{Name: "a", Length: 1, SHA1: "short"},
+			errMessage: "torrentdata: file [a] has malformed sha1",
+		},
+		{
+			name: "LengthAndFiles",
+			info: InfoDictionary{
+				Name:   "root",
+				Length: 10,
+				Files:  []FileDictionary{{Length: 10, Path: []string{"a"}}},
+			},
+			errMessage: "torrentdata: both length and files are present",
+		},
+		{
+			name:       "EmptyPath",
+			info:       InfoDictionary{Name: "root", Files: []FileDictionary{{Length: 10}}},
+			errMessage: "torrentdata: file 0 has empty path",
+		},
+		{
+			name:       "NegativeLength",
+			info:       InfoDictionary{Name: "root", Files: []FileDictionary{{Length: -1, Path: []string{"a"}}}},
+			errMessage: "torrentdata: file 0 has negative length",
+		},
+		{
+			name:       "EmptyName",
+			info:       InfoDictionary{Length: 1},
+			errMessage: `torrentdata: invalid name ""`,
+		},
+		{
+			name:       "TraversalName",
+			info:       InfoDictionary{Name: "..", Files: []FileDictionary{{Length: 1, Path: []string{"a"}}}},
+			errMessage: `torrentdata: invalid name ".."`,
+		},
+		{
+			name:       "TraversalPath",
+			info:       InfoDictionary{Name: "root", Files: []FileDictionary{{Length: 1, Path: []string{"..", "x"}}}},
+			errMessage: `torrentdata: file 0 has invalid path component ".."`,
+		},
+		{
+			name:       "CurrentDirectoryPath",
+			info:       InfoDictionary{Name: "root", Files: []FileDictionary{{Length: 1, Path: []string{"a", "."}}}},
+			errMessage: `torrentdata: file 0 has invalid path component "."`,
+		},
+		{
+			name:       "SeparatorInPath",
+			info:       InfoDictionary{Name: "root", Files: []FileDictionary{{Length: 1, Path: []string{"a/b"}}}},
+			errMessage: `torrentdata: file 0 has invalid path component "a/b"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := tc.info.buildFiles()
+			if err == nil {
+				t.Error("Expected error, got nil")
+			} else if err.Error() != tc.errMessage {
+				t.Errorf("Unexpected error message: got %q, want %q", err.Error(), tc.errMessage)
+			}
+		})
+	}
+}
+
+func TestPieceSpans(t *testing.T) {
+	torrentData := TorrentData{
+		PieceHashes: make([][20]byte, 4),
+		PieceLength: 100,
+		Length:      350,
+		Files: []File{
+			{Path: []string{"root", "a"}, Length: 150, Offset: 0},
+			{Path: []string{"root", "empty"}, Length: 0, Offset: 150},
+			{Path: []string{"root", "b"}, Length: 120, Offset: 150},
+			{Path: []string{"root", "c"}, Length: 80, Offset: 270},
+		},
+	}
+
+	testCases := []struct {
+		name      string
+		index     int
+		expected  []FileSpan
+		expectErr bool
+	}{
+		{
+			name:     "WithinSingleFile",
+			index:    0,
+			expected: []FileSpan{{FileIndex: 0, FileOffset: 0, PieceOffset: 0, Length: 100}},
+		},
+		{
+			name:  "AcrossFilesSkippingEmpty",
+			index: 1,
+			expected: []FileSpan{
+				{FileIndex: 0, FileOffset: 100, PieceOffset: 0, Length: 50},
+				{FileIndex: 2, FileOffset: 0, PieceOffset: 50, Length: 50},
+			},
+		},
+		{
+			name:  "ShortLastPiece",
+			index: 3,
+			expected: []FileSpan{
+				{FileIndex: 3, FileOffset: 30, PieceOffset: 0, Length: 50},
+			},
+		},
+		{
+			name:      "IndexOutOfRange",
+			index:     4,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spans, err := torrentData.PieceSpans(tc.index)
+			if tc.expectErr {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(spans, tc.expected) {
+				t.Errorf("Unexpected spans. Expected: %v, Got: %v", tc.expected, spans)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package torrentdata
+
+import (
+	"fmt"
+	"slices"
+)
+
+// addV2View validates the v2 file tree of a hybrid torrent against its v1 file list and
+// adds the v2 info hash, pieces roots and piece layers to the v1 torrent data.
+func (metainfoFile *MetainfoFile) addV2View(t *TorrentData) error {
+	v2Files, _, err := metainfoFile.Info.buildV2Files()
+	if err != nil {
+		return err
+	}
+	if err := matchHybridFiles(t.Files, v2Files, t.PieceLength); err != nil {
+		return err
+	}
+	if numPieces := (t.Length + t.PieceLength - 1) / t.PieceLength; numPieces != len(t.PieceHashes) {
+		return fmt.Errorf("torrentdata: expected %d piece hashes, but got %d", numPieces, len(t.PieceHashes))
+	}
+
+	layers, err := metainfoFile.decodePieceLayers(t.Files)
+	if err != nil {
+		return err
+	}
+	infoHashV2, err := metainfoFile.infoHashV2()
+	if err != nil {
+		return err
+	}
+	t.MetaVersion = MetaVersion2
+	t.InfoHashV2 = infoHashV2
+	t.PieceLayers = layers
+	t.hybrid = true
+	return nil
+}
+
+// matchHybridFiles checks that the v1 files, once padding files are skipped, describe the same
+// files in the same order as the v2 file tree, and that padding aligns every file to a piece
+// boundary. The pieces roots of the v2 files are copied onto the matching v1 files.
+func matchHybridFiles(v1Files []File, v2Files []File, pieceLength int) error {
+	next := 0
+	for i := range v1Files {
+		f := &v1Files[i]
+		if f.Padding {
+			continue
+		}
+		if next >= len(v2Files) {
+			return fmt.Errorf("torrentdata: v1 file %v is missing from the file tree", f.Path)
+		}
+		v2File := v2Files[next]
+		next++
+		if !slices.Equal(f.Path, v2File.Path) || f.Length != v2File.Length {
+			return fmt.Errorf("torrentdata: v1 file %v (%d bytes) does not match v2 file %v (%d bytes)", f.Path, f.Length, v2File.Path, v2File.Length)
+		}
+		f.PiecesRoot = v2File.PiecesRoot
+
+		if f.Offset%pieceLength != 0 && f.Length > 0 {
+			return fmt.Errorf("torrentdata: file %v is not aligned to a piece boundary", f.Path)
+		}
+		// Only files followed by more data need padding; empty files occupy no pieces.
+		if remainder := f.Length % pieceLength; remainder != 0 && hasData(v2Files[next:]) {
+			if i+1 >= len(v1Files) || !v1Files[i+1].Padding || v1Files[i+1].Length != pieceLength-remainder {
+				return fmt.Errorf("torrentdata: file %v is not followed by %d bytes of padding", f.Path, pieceLength-remainder)
+			}
+		}
+	}
+	if next != len(v2Files) {
+		return fmt.Errorf("torrentdata: v2 file %v is missing from the v1 file list", v2Files[next].Path)
+	}
+	return nil
+}
+
+func hasData(files []File) bool {
+	for _, f := range files {
+		if f.Length > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// IsHybrid tells if the torrent carries both v1 and v2 metadata describing the same files.
+func (t *TorrentData) IsHybrid() bool {
+	return t.hybrid
+}
+
+// SwarmHashes returns the 20-byte info hashes identifying the swarms of the torrent: the
+// v1 info hash, followed by the truncated v2 info hash for hybrid torrents.
+func (t *TorrentData) SwarmHashes() [][20]byte {
+	if t.hybrid {
+		return [][20]byte{t.InfoHash, t.TruncatedInfoHashV2()}
+	}
+	return [][20]byte{t.InfoHash}
+}
@@ -0,0 +1,136 @@
+package torrentdata
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"strings"
+	"testing"
+)
+
+// hybridFixture describes a hybrid torrent with two files separated by a padding file.
+type hybridFixture struct {
+	a, b     []byte
+	v1Files  []interface{}
+	info     map[string]interface{}
+	layers   map[string]interface{}
+	metainfo map[string]interface{}
+}
+
+func newHybridFixture() *hybridFixture {
+	f := &hybridFixture{a: patternData(20000, 7), b: patternData(120000, 251)}
+	padding := v2PieceLength - len(f.a)
+
+	var data []byte
+	data = append(data, f.a...)
+	data = append(data, make([]byte, padding)...)
+	data = append(data, f.b...)
+
+	var pieces strings.Builder
+	for begin := 0; begin < len(data); begin += v2PieceLength {
+		h := sha1.Sum(data[begin:min(begin+v2PieceLength, len(data))])
+		pieces.Write(h[:])
+	}
+
+	f.v1Files = []interface{}{
+		map[string]interface{}{"length": int64(len(f.a)), "path": []interface{}{"a.txt"}},
+		map[string]interface{}{"length": int64(padding), "path": []interface{}{".pad", "12768"}, "attr": "p"},
+		map[string]interface{}{"length": int64(len(f.b)), "path": []interface{}{"b.bin"}},
+	}
+	f.info = map[string]interface{}{
+		"meta version": int64(MetaVersion2),
+		"name":         "hybrid",
+		"piece length": int64(v2PieceLength),
+		"pieces":       pieces.String(),
+		"files":        f.v1Files,
+		"file tree": map[string]interface{}{
+			"a.txt": fileTreeLeaf(f.a),
+			"b.bin": fileTreeLeaf(f.b),
+		},
+	}
+	bRoot := piecesRoot(f.b, v2PieceLength)
+	f.layers = map[string]interface{}{string(bRoot[:]): encodeLayer(pieceLayer(f.b, v2PieceLength))}
+	f.metainfo = map[string]interface{}{"info": f.info, "piece layers": f.layers}
+	return f
+}
+
+func (f *hybridFixture) open(t *testing.T) (*MetainfoFile, TorrentData, error) {
+	t.Helper()
+	metainfoFile, err := Open(writeTorrent(t, f.metainfo))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	torrentData, err := metainfoFile.toTorrentData()
+	return metainfoFile, torrentData, err
+}
+
+func TestOpenHybrid(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		f := newHybridFixture()
+		metainfoFile, torrentData, err := f.open(t)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if !torrentData.IsHybrid() || torrentData.MetaVersion != MetaVersion2 {
+			t.Errorf("Expected a hybrid v2 torrent, got MetaVersion %d", torrentData.MetaVersion)
+		}
+		if expected := sha1.Sum(metainfoFile.rawInfo); torrentData.InfoHash != expected {
+			t.Errorf("Unexpected InfoHash: got %x, want %x", torrentData.InfoHash, expected)
+		}
+		if expected := sha256.Sum256(metainfoFile.rawInfo); torrentData.InfoHashV2 != expected {
+			t.Errorf("Unexpected InfoHashV2: got %x, want %x", torrentData.InfoHashV2, expected)
+		}
+		hashes := torrentData.SwarmHashes()
+		if len(hashes) != 2 || hashes[0] != torrentData.InfoHash || hashes[1] != torrentData.TruncatedInfoHashV2() {
+			t.Errorf("Unexpected swarm hashes: %x", hashes)
+		}
+		if !torrentData.Files[1].Padding {
+			t.Error("Expected the second file to be padding")
+		}
+		if torrentData.Files[0].PiecesRoot != piecesRoot(f.a, v2PieceLength) || torrentData.Files[2].PiecesRoot != piecesRoot(f.b, v2PieceLength) {
+			t.Error("Pieces roots were not copied onto the v1 files")
+		}
+	})
+
+	testCases := []struct {
+		name       string
+		modify     func(f *hybridFixture)
+		errMessage string
+	}{
+		{
+			name: "MissingPadding",
+			modify: func(f *hybridFixture) {
+				f.info["files"] = []interface{}{f.v1Files[0], f.v1Files[2]}
+			},
+			errMessage: "torrentdata: invalid hybrid torrent: torrentdata: file [hybrid a.txt] is not followed by 12768 bytes of padding",
+		},
+		{
+			name: "DifferentLength",
+			modify: func(f *hybridFixture) {
+				f.info["file tree"].(map[string]interface{})["b.bin"] = fileTreeLeaf(f.b[:110000])
+			},
+			errMessage: "torrentdata: invalid hybrid torrent: torrentdata: v1 file [hybrid b.bin] (120000 bytes) does not match v2 file [hybrid b.bin] (110000 bytes)",
+		},
+		{
+			name: "ExtraV2File",
+			modify: func(f *hybridFixture) {
+				f.info["file tree"].(map[string]interface{})["c.txt"] = fileTreeLeaf(nil)
+			},
+			errMessage: "torrentdata: invalid hybrid torrent: torrentdata: v2 file [hybrid c.txt] is missing from the v1 file list",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newHybridFixture()
+			tc.modify(f)
+
+			_, _, err := f.open(t)
+			if err == nil {
+				t.Error("Expected error, got nil")
+			} else if err.Error() != tc.errMessage {
+				t.Errorf("Unexpected error message: got %q, want %q", err.Error(), tc.errMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package torrentdata
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	defaultFetchMaxSize      = 10 << 20
+	defaultFetchTimeout      = 30 * time.Second
+	defaultFetchMaxRedirects = 5
+)
+
+// ErrTooLarge is returned when a parsed or fetched torrent exceeds its size limit.
+var ErrTooLarge = errors.New("torrentdata: torrent file too large")
+
+// FetchOptions configures how Fetch downloads a torrent file. Zero values select the defaults.
+type FetchOptions struct {
+	// MaxSize limits the size of the response body in bytes. Defaults to 10 MiB.
+	MaxSize int64
+	// Timeout bounds the whole request, including redirects and reading the body. Defaults to 30s.
+	Timeout time.Duration
+	// MaxRedirects caps the number of redirects followed. Defaults to 5; negative disables redirects.
+	MaxRedirects int
+	// HTTPClient is used for the request. Its CheckRedirect is replaced to enforce MaxRedirects.
+	HTTPClient *http.Client
+}
+
+// Parse reads a bencoded torrent file of at most 10 MiB from r and returns its processed
+// data. Larger input yields ErrTooLarge.
+func Parse(r io.Reader) (*TorrentData, error) {
+	data, err := readLimited(r, defaultFetchMaxSize)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBytes(data)
+}
+
+// readLimited reads all of r, failing with ErrTooLarge once more than maxSize bytes arrive.
+func readLimited(r io.Reader, maxSize int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("torrentdata: failed to read torrent: %w", err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("%w: exceeds limit of %d bytes", ErrTooLarge, maxSize)
+	}
+	return data, nil
+}
+
+// ParseBytes parses a bencoded torrent file and returns its processed data.
+func ParseBytes(data []byte) (*TorrentData, error) {
+	metainfoFile, err := decodeMetainfo(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	t, err := metainfoFile.toTorrentData()
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Fetch downloads a torrent file from an http or https URL and returns its processed data.
+func Fetch(ctx context.Context, rawURL string, opts FetchOptions) (*TorrentData, error) {
+	if err := checkFetchURL(rawURL); err != nil {
+		return nil, err
+	}
+	maxSize := opts.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultFetchMaxSize
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultFetchTimeout
+	}
+	maxRedirects := opts.MaxRedirects
+	if maxRedirects == 0 {
+		maxRedirects = defaultFetchMaxRedirects
+	}
+
+	client := http.Client{}
+	if opts.HTTPClient != nil {
+		client = *opts.HTTPClient
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxRedirects {
+			return fmt.Errorf("torrentdata: stopped after %d redirects", max(maxRedirects, 0))
+		}
+		return checkFetchURL(req.URL.String())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("torrentdata: failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/x-bittorrent")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("torrentdata: failed to fetch torrent: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("torrentdata: unexpected status %s from %s", resp.Status, resp.Request.URL)
+	}
+	if resp.ContentLength > maxSize {
+		return nil, fmt.Errorf("%w: %d bytes exceeds limit of %d", ErrTooLarge, resp.ContentLength, maxSize)
+	}
+	data, err := readLimited(resp.Body, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBytes(data)
+}
+
+// checkFetchURL rejects URLs that Fetch cannot or should not follow.
+func checkFetchURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("torrentdata: invalid URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("torrentdata: unsupported URL scheme %q", u.Scheme)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package torrentdata
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/bencode"
+)
+
+const archTorrent = "../../test/data/archlinux-2019.12.01-x86_64.iso.torrent"
+
+func TestParse(t *testing.T) {
+	data, err := os.ReadFile(archTorrent)
+	if err != nil {
+		t.Fatalf("Failed to read torrent: %v", err)
+	}
+
+	t.Run("Reader", func(t *testing.T) {
+		torrent, err := Parse(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if got := fmt.Sprintf("%x", torrent.InfoHash); got != "dee86a7fa6f286a9d74c362014616a0ff5e4843d" {
+			t.Errorf("Unexpected InfoHash: got %s", got)
+		}
+		if torrent.Name != "archlinux-2019.12.01-x86_64.iso" {
+			t.Errorf("Unexpected Name: got %q", torrent.Name)
+		}
+	})
+
+	t.Run("DeeplyNested", func(t *testing.T) {
+		data := "d6:nested" + strings.Repeat("l", 1000) + strings.Repeat("e", 1000) + "e"
+		if _, err := ParseBytes([]byte(data)); !errors.Is(err, bencode.ErrLimitExceeded) {
+			t.Errorf("Unexpected error: got %v, want %v", err, bencode.ErrLimitExceeded)
+		}
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		if _, err := Parse(bytes.NewReader(make([]byte, defaultFetchMaxSize+1))); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("Unexpected error: got %v, want %v", err, ErrTooLarge)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		if _, err := ParseBytes([]byte("d4:infod6:pieces3:abcee")); err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+}
+
+func TestFetch(t *testing.T) {
+	data, err := os.ReadFile(archTorrent)
+	if err != nil {
+		t.Fatalf("Failed to read torrent: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/arch.torrent", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	})
+	mux.HandleFunc("/chunked.torrent", func(w http.ResponseWriter, r *http.Request) {
+		// Flushing before writing the body prevents a Content-Length header.
+		w.(http.Flusher).Flush()
+		w.Write(data)
+	})
+	mux.HandleFunc("/redirect/", func(w http.ResponseWriter, r *http.Request) {
+		var hops int
+		fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/redirect/"), "%d", &hops)
+		if hops == 0 {
+			http.Redirect(w, r, "/arch.torrent", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/redirect/%d", hops-1), http.StatusFound)
+	})
+	mux.HandleFunc("/ftp", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "ftp://example.com/arch.torrent", http.StatusFound)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Run("Valid", func(t *testing.T) {
+		torrent, err := Fetch(context.Background(), server.URL+"/arch.torrent", FetchOptions{})
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		if torrent.Announce != "http://tracker.archlinux.org:6969/announce" {
+			t.Errorf("Unexpected Announce: got %q", torrent.Announce)
+		}
+	})
+
+	t.Run("FollowsRedirects", func(t *testing.T) {
+		if _, err := Fetch(context.Background(), server.URL+"/redirect/2", FetchOptions{}); err != nil {
+			t.Errorf("Fetch failed: %v", err)
+		}
+	})
+
+	testCases := []struct {
+		name     string
+		path     string
+		opts     FetchOptions
+		expected string
+	}{
+		{"TooLarge", "/arch.torrent", FetchOptions{MaxSize: 1024}, "torrent file too large"},
+		{"TooLargeWithoutContentLength", "/chunked.torrent", FetchOptions{MaxSize: 1024}, "torrent file too large"},
+		{"TooManyRedirects", "/redirect/5", FetchOptions{MaxRedirects: 3}, "stopped after 3 redirects"},
+		{"RedirectsDisabled", "/redirect/0", FetchOptions{MaxRedirects: -1}, "stopped after 0 redirects"},
+		{"RedirectToUnsupportedScheme", "/ftp", FetchOptions{}, "unsupported URL scheme \"ftp\""},
+		{"NotFound", "/missing", FetchOptions{}, "unexpected status 404 Not Found"},
+		{"Timeout", "/slow", FetchOptions{Timeout: 50 * time.Millisecond}, "context deadline exceeded"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Fetch(context.Background(), server.URL+tc.path, tc.opts)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Unexpected error: got %q, want it to contain %q", err.Error(), tc.expected)
+			}
+		})
+	}
+
+	t.Run("TooLargeIsErrTooLarge", func(t *testing.T) {
+		_, err := Fetch(context.Background(), server.URL+"/arch.torrent", FetchOptions{MaxSize: 1024})
+		if !errors.Is(err, ErrTooLarge) {
+			t.Errorf("Unexpected error: got %v, want %v", err, ErrTooLarge)
+		}
+	})
+
+	t.Run("UnsupportedScheme", func(t *testing.T) {
+		if _, err := Fetch(context.Background(), "file:///etc/passwd", FetchOptions{}); err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+}
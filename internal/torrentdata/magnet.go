@@ -0,0 +1,293 @@
+package torrentdata
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	btihPrefix = "urn:btih:"
+	btmhPrefix = "urn:btmh:"
+
+	// sha256Multihash is the multihash prefix of a SHA-256 digest (function code 0x12, length 32).
+	sha256Multihash = "1220"
+)
+
+// IndexRange represents an inclusive range of file indices.
+type IndexRange struct {
+	First int
+	Last  int
+}
+
+// Magnet represents the torrent described by a magnet URI, before its metadata is available.
+type Magnet struct {
+	InfoHash   [20]byte
+	InfoHashV2 [32]byte
+	Name       string
+	Length     int
+	Trackers   []string
+	WebSeeds   []string
+	// PeerAddresses holds host:port addresses of peers to contact directly.
+	PeerAddresses []string
+	// SelectOnly lists the files to download; an empty list selects every file.
+	SelectOnly []IndexRange
+}
+
+// HasInfoHash tells if the magnet carries a v1 (SHA-1) info hash.
+func (m *Magnet) HasInfoHash() bool {
+	return m.InfoHash != [20]byte{}
+}
+
+// HasInfoHashV2 tells if the magnet carries a v2 (SHA-256) info hash.
+func (m *Magnet) HasInfoHashV2() bool {
+	return m.InfoHashV2 != [32]byte{}
+}
+
+// Selects tells if the file at the given index should be downloaded.
+func (m *Magnet) Selects(index int) bool {
+	if len(m.SelectOnly) == 0 {
+		return true
+	}
+	for _, r := range m.SelectOnly {
+		if index >= r.First && index <= r.Last {
+			return true
+		}
+	}
+	return false
+}
+
+// MagnetOptions selects the parts of the magnet built by TorrentData.Magnet. The zero value
+// includes every part that applies to the torrent.
+type MagnetOptions struct {
+	OmitInfoHash   bool
+	OmitInfoHashV2 bool
+	OmitName       bool
+	OmitLength     bool
+	OmitTrackers   bool
+	OmitWebSeeds   bool
+}
+
+// Magnet returns a magnet describing the torrent. The v1 info hash is only included for v1
+// and hybrid torrents and the v2 info hash for v2 and hybrid torrents; the trackers of all
+// tiers are listed in tier order.
+func (t *TorrentData) Magnet(opts MagnetOptions) *Magnet {
+	m := &Magnet{}
+	if !opts.OmitInfoHash && (t.MetaVersion != MetaVersion2 || t.IsHybrid()) {
+		m.InfoHash = t.InfoHash
+	}
+	if !opts.OmitInfoHashV2 && t.MetaVersion == MetaVersion2 {
+		m.InfoHashV2 = t.InfoHashV2
+	}
+	if !opts.OmitName {
+		m.Name = t.Name
+	}
+	if !opts.OmitLength {
+		m.Length = t.Length
+	}
+	if !opts.OmitTrackers {
+		for _, tier := range t.AnnounceList {
+			m.Trackers = append(m.Trackers, tier...)
+		}
+	}
+	if !opts.OmitWebSeeds {
+		m.WebSeeds = append(m.WebSeeds, t.WebSeeds...)
+	}
+	return m
+}
+
+// String encodes the magnet as a URI that ParseMagnet accepts. Zero-valued parts are left out.
+func (m *Magnet) String() string {
+	var params []string
+	add := func(key, value string) {
+		params = append(params, key+"="+value)
+	}
+	if m.HasInfoHash() {
+		add("xt", btihPrefix+hex.EncodeToString(m.InfoHash[:]))
+	}
+	if m.HasInfoHashV2() {
+		add("xt", btmhPrefix+sha256Multihash+hex.EncodeToString(m.InfoHashV2[:]))
+	}
+	if m.Name != "" {
+		add("dn", url.QueryEscape(m.Name))
+	}
+	if m.Length > 0 {
+		add("xl", strconv.Itoa(m.Length))
+	}
+	for _, tracker := range m.Trackers {
+		add("tr", url.QueryEscape(tracker))
+	}
+	for _, seed := range m.WebSeeds {
+		add("ws", url.QueryEscape(seed))
+	}
+	for _, addr := range m.PeerAddresses {
+		add("x.pe", url.QueryEscape(addr))
+	}
+	if len(m.SelectOnly) > 0 {
+		ranges := make([]string, len(m.SelectOnly))
+		for i, r := range m.SelectOnly {
+			ranges[i] = strconv.Itoa(r.First)
+			if r.Last != r.First {
+				ranges[i] += "-" + strconv.Itoa(r.Last)
+			}
+		}
+		add("so", strings.Join(ranges, ","))
+	}
+	return "magnet:?" + strings.Join(params, "&")
+}
+
+// ParseMagnet parses a magnet URI as described by BEP 9 and BEP 53.
+func ParseMagnet(uri string) (*Magnet, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("torrentdata: failed to parse magnet URI: %w", err)
+	}
+	if u.Scheme != "magnet" {
+		return nil, fmt.Errorf("torrentdata: unexpected scheme %q in magnet URI", u.Scheme)
+	}
+
+	// Parameters are walked in order so that repeated trackers keep their listed priority.
+	var m Magnet
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		rawKey, rawValue, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			return nil, fmt.Errorf("torrentdata: failed to parse magnet URI: %w", err)
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("torrentdata: failed to parse magnet URI: %w", err)
+		}
+		if err := m.setParameter(magnetParameter(key), value); err != nil {
+			return nil, err
+		}
+	}
+	if !m.HasInfoHash() && !m.HasInfoHashV2() {
+		return nil, errors.New("torrentdata: magnet URI has no info hash")
+	}
+	return &m, nil
+}
+
+// magnetParameter strips the numeric suffix used to enumerate repeated parameters, such as tr.1.
+func magnetParameter(key string) string {
+	if i := strings.LastIndexByte(key, '.'); i >= 0 {
+		if _, err := strconv.Atoi(key[i+1:]); err == nil {
+			return key[:i]
+		}
+	}
+	return key
+}
+
+func (m *Magnet) setParameter(key, value string) error {
+	switch key {
+	case "xt":
+		return m.setExactTopic(value)
+	case "dn":
+		m.Name = value
+	case "xl":
+		length, err := strconv.Atoi(value)
+		if err != nil || length < 0 {
+			return fmt.Errorf("torrentdata: invalid exact length %q", value)
+		}
+		m.Length = length
+	case "tr":
+		m.Trackers = append(m.Trackers, value)
+	case "ws":
+		m.WebSeeds = append(m.WebSeeds, value)
+	case "x.pe":
+		if _, _, err := net.SplitHostPort(value); err != nil {
+			return fmt.Errorf("torrentdata: invalid peer address %q: %w", value, err)
+		}
+		m.PeerAddresses = append(m.PeerAddresses, value)
+	case "so":
+		ranges, err := parseIndexRanges(value)
+		if err != nil {
+			return err
+		}
+		m.SelectOnly = append(m.SelectOnly, ranges...)
+	}
+	return nil
+}
+
+func (m *Magnet) setExactTopic(value string) error {
+	switch {
+	case strings.HasPrefix(value, btihPrefix):
+		hash, err := decodeBTIH(value[len(btihPrefix):])
+		if err != nil {
+			return err
+		}
+		m.InfoHash = hash
+	case strings.HasPrefix(value, btmhPrefix):
+		hash, err := decodeBTMH(value[len(btmhPrefix):])
+		if err != nil {
+			return err
+		}
+		m.InfoHashV2 = hash
+	}
+	return nil
+}
+
+// decodeBTIH decodes a v1 info hash given as 40 hex or 32 base32 characters.
+func decodeBTIH(s string) ([20]byte, error) {
+	var hash [20]byte
+	var decoded []byte
+	var err error
+	switch len(s) {
+	case hex.EncodedLen(len(hash)):
+		decoded, err = hex.DecodeString(s)
+	case base32.StdEncoding.EncodedLen(len(hash)):
+		decoded, err = base32.StdEncoding.DecodeString(strings.ToUpper(s))
+	default:
+		return hash, fmt.Errorf("torrentdata: invalid btih length %d", len(s))
+	}
+	if err != nil {
+		return hash, fmt.Errorf("torrentdata: invalid btih %q: %w", s, err)
+	}
+	copy(hash[:], decoded)
+	return hash, nil
+}
+
+// decodeBTMH decodes a v2 info hash given as a hex-encoded SHA-256 multihash.
+func decodeBTMH(s string) ([32]byte, error) {
+	var hash [32]byte
+	if !strings.HasPrefix(s, sha256Multihash) {
+		return hash, fmt.Errorf("torrentdata: unsupported btmh multihash %q", s)
+	}
+	digest := s[len(sha256Multihash):]
+	if len(digest) != hex.EncodedLen(len(hash)) {
+		return hash, fmt.Errorf("torrentdata: invalid btmh length %d", len(s))
+	}
+	if _, err := hex.Decode(hash[:], []byte(digest)); err != nil {
+		return hash, fmt.Errorf("torrentdata: invalid btmh %q: %w", s, err)
+	}
+	return hash, nil
+}
+
+// parseIndexRanges parses a BEP 53 file selection such as "0,2,4-6".
+func parseIndexRanges(s string) ([]IndexRange, error) {
+	var ranges []IndexRange
+	for _, part := range strings.Split(s, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		begin, err := strconv.Atoi(first)
+		if err != nil || begin < 0 {
+			return nil, fmt.Errorf("torrentdata: invalid file selection %q", s)
+		}
+		end := begin
+		if isRange {
+			end, err = strconv.Atoi(last)
+			if err != nil || end < begin {
+				return nil, fmt.Errorf("torrentdata: invalid file selection %q", s)
+			}
+		}
+		ranges = append(ranges, IndexRange{First: begin, Last: end})
+	}
+	return ranges, nil
+}
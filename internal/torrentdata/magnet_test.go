@@ -0,0 +1,200 @@
+package torrentdata
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestParseMagnet(t *testing.T) {
+	infoHash, _ := hex.DecodeString("dee86a7fa6f286a9d74c362014616a0ff5e4843d")
+	infoHashV2, _ := hex.DecodeString("d8dd32ac93357c368556af3ac1d95c9d76bd0dff6fa9833ecdac3d53134efabb")
+
+	t.Run("HexInfoHash", func(t *testing.T) {
+		m, err := ParseMagnet("magnet:?xt=urn:btih:DEE86A7FA6F286A9D74C362014616A0FF5E4843D" +
+			"&dn=archlinux-2019.12.01-x86_64.iso&xl=670040064" +
+			"&tr=http%3A%2F%2Ftracker.archlinux.org%3A6969%2Fannounce&tr.1=udp%3A%2F%2Ftracker.example%3A1337" +
+			"&ws=http%3A%2F%2Fmirror.example%2Fiso%2F&x.pe=10.0.0.1%3A6881&x.pe=%5B%3A%3A1%5D%3A51413")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := &Magnet{
+			Name:          "archlinux-2019.12.01-x86_64.iso",
+			Length:        670040064,
+			Trackers:      []string{"http://tracker.archlinux.org:6969/announce", "udp://tracker.example:1337"},
+			WebSeeds:      []string{"http://mirror.example/iso/"},
+			PeerAddresses: []string{"10.0.0.1:6881", "[::1]:51413"},
+		}
+		copy(expected.InfoHash[:], infoHash)
+		if !reflect.DeepEqual(m, expected) {
+			t.Errorf("Unexpected magnet. Expected: %+v, Got: %+v", expected, m)
+		}
+		if !m.HasInfoHash() || m.HasInfoHashV2() {
+			t.Errorf("Unexpected hash presence: v1 %v, v2 %v", m.HasInfoHash(), m.HasInfoHashV2())
+		}
+	})
+
+	t.Run("Base32InfoHash", func(t *testing.T) {
+		m, err := ParseMagnet("magnet:?xt=urn:btih:33ugu75g6kdktv2mgyqbiylkb726jbb5")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if hex.EncodeToString(m.InfoHash[:]) != hex.EncodeToString(infoHash) {
+			t.Errorf("Unexpected InfoHash: got %x, want %x", m.InfoHash, infoHash)
+		}
+	})
+
+	t.Run("HybridWithFileSelection", func(t *testing.T) {
+		m, err := ParseMagnet("magnet:?xt=urn:btih:dee86a7fa6f286a9d74c362014616a0ff5e4843d" +
+			"&xt=urn:btmh:1220d8dd32ac93357c368556af3ac1d95c9d76bd0dff6fa9833ecdac3d53134efabb&so=0,2,4-6")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if hex.EncodeToString(m.InfoHashV2[:]) != hex.EncodeToString(infoHashV2) {
+			t.Errorf("Unexpected InfoHashV2: got %x, want %x", m.InfoHashV2, infoHashV2)
+		}
+		if !m.HasInfoHash() || !m.HasInfoHashV2() {
+			t.Errorf("Unexpected hash presence: v1 %v, v2 %v", m.HasInfoHash(), m.HasInfoHashV2())
+		}
+
+		expectedRanges := []IndexRange{{0, 0}, {2, 2}, {4, 6}}
+		if !reflect.DeepEqual(m.SelectOnly, expectedRanges) {
+			t.Errorf("Unexpected SelectOnly. Expected: %v, Got: %v", expectedRanges, m.SelectOnly)
+		}
+		for index, expected := range []bool{true, false, true, false, true, true, true, false} {
+			if got := m.Selects(index); got != expected {
+				t.Errorf("Expected Selects(%d) to be %v, but got %v", index, expected, got)
+			}
+		}
+	})
+
+	testCases := []struct {
+		name       string
+		uri        string
+		errMessage string
+	}{
+		{
+			name:       "WrongScheme",
+			uri:        "http://example.com/?xt=urn:btih:dee86a7fa6f286a9d74c362014616a0ff5e4843d",
+			errMessage: "torrentdata: unexpected scheme \"http\" in magnet URI",
+		},
+		{
+			name:       "MissingInfoHash",
+			uri:        "magnet:?dn=example",
+			errMessage: "torrentdata: magnet URI has no info hash",
+		},
+		{
+			name:       "ShortInfoHash",
+			uri:        "magnet:?xt=urn:btih:dee86a",
+			errMessage: "torrentdata: invalid btih length 6",
+		},
+		{
+			name:       "UnsupportedMultihash",
+			uri:        "magnet:?xt=urn:btmh:1114abcd",
+			errMessage: "torrentdata: unsupported btmh multihash \"1114abcd\"",
+		},
+		{
+			name:       "InvalidLength",
+			uri:        "magnet:?xt=urn:btih:dee86a7fa6f286a9d74c362014616a0ff5e4843d&xl=-1",
+			errMessage: "torrentdata: invalid exact length \"-1\"",
+		},
+		{
+			name:       "InvalidSelection",
+			uri:        "magnet:?xt=urn:btih:dee86a7fa6f286a9d74c362014616a0ff5e4843d&so=4-2",
+			errMessage: "torrentdata: invalid file selection \"4-2\"",
+		},
+		{
+			name:       "InvalidPeerAddress",
+			uri:        "magnet:?xt=urn:btih:dee86a7fa6f286a9d74c362014616a0ff5e4843d&x.pe=10.0.0.1",
+			errMessage: "torrentdata: invalid peer address \"10.0.0.1\": address 10.0.0.1: missing port in address",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseMagnet(tc.uri)
+			if err == nil {
+				t.Error("Expected error, got nil")
+			} else if err.Error() != tc.errMessage {
+				t.Errorf("Unexpected error message: got %q, want %q", err.Error(), tc.errMessage)
+			}
+		})
+	}
+}
+
+func TestTorrentDataMagnet(t *testing.T) {
+	metainfoFile, err := Open("../../test/data/archlinux-2019.12.01-x86_64.iso.torrent")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	torrent, err := metainfoFile.toTorrentData()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	torrent.AnnounceList = [][]string{{"http://tracker.archlinux.org:6969/announce"}, {"udp://tracker.example:1337"}}
+	torrent.WebSeeds = torrent.WebSeeds[:1]
+
+	testCases := []struct {
+		name     string
+		opts     MagnetOptions
+		expected string
+	}{
+		{
+			name: "Everything",
+			expected: "magnet:?xt=urn:btih:dee86a7fa6f286a9d74c362014616a0ff5e4843d" +
+				"&dn=archlinux-2019.12.01-x86_64.iso&xl=670040064" +
+				"&tr=http%3A%2F%2Ftracker.archlinux.org%3A6969%2Fannounce&tr=udp%3A%2F%2Ftracker.example%3A1337" +
+				"&ws=http%3A%2F%2Fmirrors.evowise.com%2Farchlinux%2Fiso%2F2019.12.01%2F",
+		},
+		{
+			name:     "InfoHashOnly",
+			opts:     MagnetOptions{OmitName: true, OmitLength: true, OmitTrackers: true, OmitWebSeeds: true},
+			expected: "magnet:?xt=urn:btih:dee86a7fa6f286a9d74c362014616a0ff5e4843d",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			uri := torrent.Magnet(tc.opts).String()
+			if uri != tc.expected {
+				t.Errorf("Unexpected magnet URI:\ngot  %s\nwant %s", uri, tc.expected)
+			}
+			parsed, err := ParseMagnet(uri)
+			if err != nil {
+				t.Fatalf("ParseMagnet failed: %v", err)
+			}
+			if !reflect.DeepEqual(parsed, torrent.Magnet(tc.opts)) {
+				t.Errorf("Round trip mismatch: got %+v, want %+v", parsed, torrent.Magnet(tc.opts))
+			}
+		})
+	}
+
+	t.Run("Hybrid", func(t *testing.T) {
+		hybrid := &TorrentData{MetaVersion: MetaVersion2, InfoHash: [20]byte{1}, InfoHashV2: [32]byte{2}, hybrid: true}
+		m := hybrid.Magnet(MagnetOptions{})
+		if !m.HasInfoHash() || !m.HasInfoHashV2() {
+			t.Errorf("Unexpected hash presence: v1 %v, v2 %v", m.HasInfoHash(), m.HasInfoHashV2())
+		}
+		m = hybrid.Magnet(MagnetOptions{OmitInfoHash: true})
+		if m.HasInfoHash() || !m.HasInfoHashV2() {
+			t.Errorf("Unexpected hash presence: v1 %v, v2 %v", m.HasInfoHash(), m.HasInfoHashV2())
+		}
+	})
+
+	t.Run("V2Only", func(t *testing.T) {
+		v2 := &TorrentData{MetaVersion: MetaVersion2, InfoHash: [20]byte{1}, InfoHashV2: [32]byte{2}}
+		expected := "magnet:?xt=urn:btmh:12200200000000000000000000000000000000000000000000000000000000000000"
+		if uri := v2.Magnet(MagnetOptions{}).String(); uri != expected {
+			t.Errorf("Unexpected magnet URI: got %s, want %s", uri, expected)
+		}
+	})
+
+	t.Run("SelectOnly", func(t *testing.T) {
+		m := &Magnet{InfoHash: [20]byte{1}, SelectOnly: []IndexRange{{0, 0}, {2, 2}, {4, 6}}, PeerAddresses: []string{"[::1]:51413"}}
+		expected := "magnet:?xt=urn:btih:0100000000000000000000000000000000000000&x.pe=%5B%3A%3A1%5D%3A51413&so=0,2,4-6"
+		if uri := m.String(); uri != expected {
+			t.Errorf("Unexpected magnet URI: got %s, want %s", uri, expected)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package torrentdata
+
+import "crypto/sha256"
+
+// BlockSize is the size of the leaf blocks of BitTorrent v2 merkle trees.
+const BlockSize = 16 * 1024
+
+// hashBlocks returns the leaf hashes of data split into BlockSize blocks.
+func hashBlocks(data []byte) [][32]byte {
+	var leaves [][32]byte
+	for begin := 0; begin < len(data); begin += BlockSize {
+		end := min(begin+BlockSize, len(data))
+		leaves = append(leaves, sha256.Sum256(data[begin:end]))
+	}
+	return leaves
+}
+
+// merkleRoot reduces a layer of hashes to its root, padding the layer to a power of
+// two with copies of padding, the hash of an empty subtree at that layer.
+func merkleRoot(layer [][32]byte, padding [32]byte) [32]byte {
+	if len(layer) == 0 {
+		return [32]byte{}
+	}
+	width := 1
+	for width < len(layer) {
+		width *= 2
+	}
+	nodes := make([][32]byte, width)
+	copy(nodes, layer)
+	for i := len(layer); i < width; i++ {
+		nodes[i] = padding
+	}
+	for len(nodes) > 1 {
+		for i := 0; i < len(nodes)/2; i++ {
+			nodes[i] = hashPair(nodes[2*i], nodes[2*i+1])
+		}
+		nodes = nodes[:len(nodes)/2]
+	}
+	return nodes[0]
+}
+
+// emptySubtreeHash returns the root of a subtree with the given number of zero leaves,
+// which must be a power of two.
+func emptySubtreeHash(leaves int) [32]byte {
+	var h [32]byte
+	for ; leaves > 1; leaves /= 2 {
+		h = hashPair(h, h)
+	}
+	return h
+}
+
+func hashPair(left, right [32]byte) [32]byte {
+	var buf [64]byte
+	copy(buf[:32], left[:])
+	copy(buf[32:], right[:])
+	return sha256.Sum256(buf[:])
+}
+
+// pieceLayer returns the hashes of the subtrees covering each piece of data.
+func pieceLayer(data []byte, pieceLength int) [][32]byte {
+	leavesPerPiece := pieceLength / BlockSize
+	leaves := hashBlocks(data)
+	var layer [][32]byte
+	for begin := 0; begin < len(leaves); begin += leavesPerPiece {
+		end := min(begin+leavesPerPiece, len(leaves))
+		layer = append(layer, pieceRoot(leaves[begin:end], leavesPerPiece))
+	}
+	return layer
+}
+
+// pieceRoot reduces the leaves of a single piece, padding them to leavesPerPiece with zero hashes.
+func pieceRoot(leaves [][32]byte, leavesPerPiece int) [32]byte {
+	padded := make([][32]byte, leavesPerPiece)
+	copy(padded, leaves)
+	return merkleRoot(padded, [32]byte{})
+}
+
+// piecesRoot computes the merkle root of a file from its data.
+func piecesRoot(data []byte, pieceLength int) [32]byte {
+	if len(data) <= pieceLength {
+		return merkleRoot(hashBlocks(data), [32]byte{})
+	}
+	return piecesRootFromLayer(pieceLayer(data, pieceLength), pieceLength)
+}
+
+// piecesRootFromLayer computes the merkle root of a file from its piece layer.
+func piecesRootFromLayer(layer [][32]byte, pieceLength int) [32]byte {
+	return merkleRoot(layer, emptySubtreeHash(pieceLength/BlockSize))
+}
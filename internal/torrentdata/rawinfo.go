@@ -0,0 +1,36 @@
+package torrentdata
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mattheworford/gotorrent/internal/bencode"
+)
+
+// splitMetainfo decodes a metainfo file from r in a single pass, reading at most
+// bencode.DefaultLimits.MaxSize bytes. It returns the exact bencoded bytes of the info
+// dictionary, along with those of every top-level value keyed by name.
+func splitMetainfo(r io.Reader) ([]byte, map[string]bencode.RawMessage, error) {
+	d := bencode.NewDecoder(io.LimitReader(r, bencode.DefaultLimits.MaxSize+1))
+	var metainfo map[string]bencode.RawMessage
+	err := d.Decode(&metainfo)
+	if err == nil {
+		err = d.ExpectEOF()
+	}
+	if err != nil {
+		var typeErr *bencode.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, nil, errors.New("torrentdata: metainfo is not a dictionary")
+		}
+		return nil, nil, fmt.Errorf("torrentdata: %w", err)
+	}
+	raw, ok := metainfo["info"]
+	if !ok {
+		return nil, nil, errors.New("torrentdata: missing info dictionary")
+	}
+	if raw[0] != 'd' {
+		return nil, nil, errors.New("torrentdata: info is not a dictionary")
+	}
+	return raw, metainfo, nil
+}
@@ -0,0 +1,126 @@
+package torrentdata
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitMetainfo(t *testing.T) {
+	testCases := []struct {
+		name       string
+		data       string
+		expected   string
+		errMessage string
+	}{
+		{
+			name:     "InfoLast",
+			data:     "d8:announce3:url4:infod4:name1:aee",
+			expected: "d4:name1:ae",
+		},
+		{
+			name:     "InfoFollowedByKeys",
+			data:     "d4:infod5:filesld6:lengthi1e4:pathl1:aeee7:privatei1ee3:zzzli1ei2eee",
+			expected: "d5:filesld6:lengthi1e4:pathl1:aeee7:privatei1ee",
+		},
+		{
+			name:       "MissingInfo",
+			data:       "d8:announce3:urle",
+			errMessage: "torrentdata: missing info dictionary",
+		},
+		{
+			name:       "InfoNotDictionary",
+			data:       "d4:info3:abce",
+			errMessage: "torrentdata: info is not a dictionary",
+		},
+		{
+			name:       "Truncated",
+			data:       "d4:infod4:name10:abc",
+			errMessage: "torrentdata: unexpected EOF",
+		},
+		{
+			name:       "NotDictionary",
+			data:       "li1ee",
+			errMessage: "torrentdata: metainfo is not a dictionary",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			raw, _, err := splitMetainfo(strings.NewReader(tc.data))
+			if tc.errMessage != "" {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				} else if err.Error() != tc.errMessage {
+					t.Errorf("Unexpected error message: got %q, want %q", err.Error(), tc.errMessage)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(raw) != tc.expected {
+				t.Errorf("Unexpected raw info: got %q, want %q", raw, tc.expected)
+			}
+		})
+	}
+}
+
+func TestInfoHashFromRawBytes(t *testing.T) {
+	t.Run("ArchLinux", func(t *testing.T) {
+		metainfoFile, err := Open("../../test/data/archlinux-2019.12.01-x86_64.iso.torrent")
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		torrentData, err := metainfoFile.toTorrentData()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := "dee86a7fa6f286a9d74c362014616a0ff5e4843d"
+		if got := hex.EncodeToString(torrentData.InfoHash[:]); got != expected {
+			t.Errorf("Unexpected InfoHash: got %s, want %s", got, expected)
+		}
+	})
+
+	t.Run("UnknownKeys", func(t *testing.T) {
+		info := "d5:filesld6:lengthi20e6:md5sum32:0123456789abcdef0123456789abcdef4:pathl5:a.txteed4:attr1:p6:lengthi12e4:pathl4:.pad2:12eee" +
+			"4:name4:root12:piece lengthi32e6:pieces20:abcdefghijklmnopqrst7:privatei1e6:source3:CIAe"
+		data := "d8:announce22:http://tracker.example4:info" + info + "e"
+
+		path := filepath.Join(t.TempDir(), "unknown.torrent")
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatalf("Failed to write torrent: %v", err)
+		}
+
+		metainfoFile, err := Open(path)
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		torrentData, err := metainfoFile.toTorrentData()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := sha1.Sum([]byte(info))
+		if torrentData.InfoHash != expected {
+			t.Errorf("Unexpected InfoHash: got %x, want %x", torrentData.InfoHash, expected)
+		}
+		reencoded, err := metainfoFile.Info.computeHash()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if reencoded == expected {
+			t.Error("Expected re-encoded hash to differ from the raw info hash")
+		}
+		if !torrentData.Private {
+			t.Error("Expected the torrent to be private")
+		}
+		if torrentData.Length != 32 {
+			t.Errorf("Unexpected Length value. Expected: %d, Got: %d", 32, torrentData.Length)
+		}
+	})
+}
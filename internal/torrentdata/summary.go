@@ -0,0 +1,153 @@
+package torrentdata
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Summary describes a torrent for display or for consumption by other tools. Its JSON
+// encoding is stable: every key is always present, except for the info hashes that do not
+// apply to the torrent's version.
+type Summary struct {
+	Name        string        `json:"name"`
+	InfoHash    string        `json:"info_hash,omitempty"`
+	InfoHashV2  string        `json:"info_hash_v2,omitempty"`
+	MetaVersion int           `json:"meta_version"`
+	Hybrid      bool          `json:"hybrid"`
+	Private     bool          `json:"private"`
+	Length      int           `json:"length"`
+	PieceLength int           `json:"piece_length"`
+	PieceCount  int           `json:"piece_count"`
+	Trackers    [][]string    `json:"trackers"`
+	WebSeeds    []string      `json:"web_seeds"`
+	Files       []FileSummary `json:"files"`
+}
+
+// FileSummary describes a file of a torrent. Path components are joined with slashes.
+type FileSummary struct {
+	Path    string `json:"path"`
+	Length  int    `json:"length"`
+	Offset  int    `json:"offset"`
+	Padding bool   `json:"padding"`
+}
+
+// Summary returns the description of the torrent.
+func (t *TorrentData) Summary() Summary {
+	s := Summary{
+		Name:        t.Name,
+		MetaVersion: t.MetaVersion,
+		Hybrid:      t.IsHybrid(),
+		Private:     t.Private,
+		Length:      t.Length,
+		PieceLength: t.PieceLength,
+		PieceCount:  t.pieceCount(),
+		Trackers:    [][]string{},
+		WebSeeds:    []string{},
+		Files:       make([]FileSummary, len(t.Files)),
+	}
+	if t.MetaVersion != MetaVersion2 || s.Hybrid {
+		s.InfoHash = hex.EncodeToString(t.InfoHash[:])
+	}
+	if t.MetaVersion == MetaVersion2 {
+		s.InfoHashV2 = hex.EncodeToString(t.InfoHashV2[:])
+	}
+	if len(t.AnnounceList) > 0 {
+		s.Trackers = t.AnnounceList
+	}
+	if len(t.WebSeeds) > 0 {
+		s.WebSeeds = t.WebSeeds
+	}
+	for i, f := range t.Files {
+		s.Files[i] = FileSummary{Path: strings.Join(f.Path, "/"), Length: f.Length, Offset: f.Offset, Padding: f.Padding}
+	}
+	return s
+}
+
+// pieceCount returns the number of pieces, which for v2-only torrents start afresh in each file.
+func (t *TorrentData) pieceCount() int {
+	if len(t.PieceHashes) > 0 || t.PieceLength <= 0 {
+		return len(t.PieceHashes)
+	}
+	count := 0
+	for _, f := range t.Files {
+		count += (f.Length + t.PieceLength - 1) / t.PieceLength
+	}
+	return count
+}
+
+// WriteJSON writes the summary as indented JSON.
+func (s Summary) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// WriteText writes the summary in a human-readable layout.
+func (s Summary) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Name:\t%s\n", s.Name)
+	if s.InfoHash != "" {
+		fmt.Fprintf(tw, "Info hash:\t%s\n", s.InfoHash)
+	}
+	if s.InfoHashV2 != "" {
+		fmt.Fprintf(tw, "Info hash v2:\t%s\n", s.InfoHashV2)
+	}
+	version := fmt.Sprint(s.MetaVersion)
+	if s.Hybrid {
+		version += " (hybrid)"
+	}
+	fmt.Fprintf(tw, "Meta version:\t%s\n", version)
+	fmt.Fprintf(tw, "Private:\t%v\n", s.Private)
+	fmt.Fprintf(tw, "Length:\t%d (%s)\n", s.Length, formatSize(s.Length))
+	fmt.Fprintf(tw, "Piece length:\t%d (%s)\n", s.PieceLength, formatSize(s.PieceLength))
+	fmt.Fprintf(tw, "Pieces:\t%d\n", s.PieceCount)
+	for i, tier := range s.Trackers {
+		label := ""
+		if i == 0 {
+			label = "Trackers:"
+		}
+		fmt.Fprintf(tw, "%s\ttier %d: %s\n", label, i+1, strings.Join(tier, ", "))
+	}
+	for i, seed := range s.WebSeeds {
+		label := ""
+		if i == 0 {
+			label = "Web seeds:"
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", label, seed)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "OFFSET\tLENGTH\t PATH")
+	for _, f := range s.Files {
+		path := f.Path
+		if f.Padding {
+			path += " (padding)"
+		}
+		fmt.Fprintf(tw, "%d\t%d\t %s\n", f.Offset, f.Length, path)
+	}
+	return tw.Flush()
+}
+
+// formatSize renders a byte count with a binary unit.
+func formatSize(n int) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value := float64(n)
+	suffixes := []string{"KiB", "MiB", "GiB", "TiB", "PiB"}
+	i := -1
+	for value >= unit && i < len(suffixes)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", value, suffixes[i])
+}
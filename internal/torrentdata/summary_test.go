@@ -0,0 +1,105 @@
+package torrentdata
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSummary(t *testing.T) {
+	torrent := &TorrentData{
+		Announce:     "http://a/announce",
+		AnnounceList: [][]string{{"http://a/announce"}},
+		InfoHash:     [20]byte{0xde, 0xad, 0xbe, 0xef},
+		PieceHashes:  make([][20]byte, 3),
+		PieceLength:  32,
+		Length:       72,
+		Name:         "root",
+		Files: []File{
+			{Path: []string{"root", "a.txt"}, Length: 20},
+			{Path: []string{"root", ".pad", "12"}, Length: 12, Offset: 20, Padding: true},
+			{Path: []string{"root", "b.txt"}, Length: 40, Offset: 32},
+		},
+		Private:     true,
+		MetaVersion: 1,
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := torrent.Summary().WriteJSON(&buf); err != nil {
+			t.Fatalf("WriteJSON failed: %v", err)
+		}
+		expected := `{
+  "name": "root",
+  "info_hash": "deadbeef00000000000000000000000000000000",
+  "meta_version": 1,
+  "hybrid": false,
+  "private": true,
+  "length": 72,
+  "piece_length": 32,
+  "piece_count": 3,
+  "trackers": [
+    [
+      "http://a/announce"
+    ]
+  ],
+  "web_seeds": [],
+  "files": [
+    {
+      "path": "root/a.txt",
+      "length": 20,
+      "offset": 0,
+      "padding": false
+    },
+    {
+      "path": "root/.pad/12",
+      "length": 12,
+      "offset": 20,
+      "padding": true
+    },
+    {
+      "path": "root/b.txt",
+      "length": 40,
+      "offset": 32,
+      "padding": false
+    }
+  ]
+}
+`
+		if buf.String() != expected {
+			t.Errorf("Unexpected JSON:\ngot  %s\nwant %s", buf.String(), expected)
+		}
+	})
+
+	t.Run("Text", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := torrent.Summary().WriteText(&buf); err != nil {
+			t.Fatalf("WriteText failed: %v", err)
+		}
+		for _, line := range []string{
+			"Info hash:     deadbeef00000000000000000000000000000000\n",
+			"Private:       true\n",
+			"Trackers:      tier 1: http://a/announce\n",
+			"     20      12 root/.pad/12 (padding)\n",
+		} {
+			if !strings.Contains(buf.String(), line) {
+				t.Errorf("Expected output to contain %q, got:\n%s", line, buf.String())
+			}
+		}
+	})
+
+	t.Run("V2PieceCount", func(t *testing.T) {
+		v2 := &TorrentData{
+			PieceLength: 16,
+			MetaVersion: MetaVersion2,
+			Files:       []File{{Length: 17}, {Length: 16, Offset: 17}, {Length: 0, Offset: 33}},
+		}
+		summary := v2.Summary()
+		if summary.PieceCount != 3 {
+			t.Errorf("Unexpected PieceCount: got %d, want %d", summary.PieceCount, 3)
+		}
+		if summary.InfoHash != "" || summary.InfoHashV2 == "" {
+			t.Errorf("Unexpected info hashes for a v2 torrent: %q, %q", summary.InfoHash, summary.InfoHashV2)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package torrentdata
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+)
+
+// announceTiers returns the tracker tiers of the metainfo file. Following BEP 12, the
+// announce-list takes precedence over announce when present; empty tiers and URLs are dropped.
+func (metainfoFile *MetainfoFile) announceTiers() [][]string {
+	tiers := cleanTiers(metainfoFile.AnnounceList)
+	if len(tiers) == 0 && metainfoFile.Announce != "" {
+		tiers = [][]string{{metainfoFile.Announce}}
+	}
+	return tiers
+}
+
+// cleanTiers drops empty tiers and tracker URLs.
+func cleanTiers(announceList [][]string) [][]string {
+	var tiers [][]string
+	for _, tier := range announceList {
+		var trackers []string
+		for _, tracker := range tier {
+			if tracker != "" {
+				trackers = append(trackers, tracker)
+			}
+		}
+		if len(trackers) > 0 {
+			tiers = append(tiers, trackers)
+		}
+	}
+	return tiers
+}
+
+// TrackerTiers manages a tiered tracker list as described by BEP 12.
+// It is safe for concurrent use.
+type TrackerTiers struct {
+	mu    sync.Mutex
+	tiers [][]string
+}
+
+// NewTrackerTiers copies the given tiers and shuffles the trackers within each tier.
+func NewTrackerTiers(tiers [][]string) *TrackerTiers {
+	return newTrackerTiers(tiers, rand.Shuffle)
+}
+
+func newTrackerTiers(tiers [][]string, shuffle func(n int, swap func(i, j int))) *TrackerTiers {
+	tt := &TrackerTiers{tiers: make([][]string, 0, len(tiers))}
+	for _, tier := range tiers {
+		if len(tier) == 0 {
+			continue
+		}
+		trackers := append([]string(nil), tier...)
+		shuffle(len(trackers), func(i, j int) {
+			trackers[i], trackers[j] = trackers[j], trackers[i]
+		})
+		tt.tiers = append(tt.tiers, trackers)
+	}
+	return tt
+}
+
+// Tiers returns a snapshot of the tiers in their current order.
+func (tt *TrackerTiers) Tiers() [][]string {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	tiers := make([][]string, len(tt.tiers))
+	for i, tier := range tt.tiers {
+		tiers[i] = append([]string(nil), tier...)
+	}
+	return tiers
+}
+
+// Promote moves a tracker that responded to the front of its tier.
+func (tt *TrackerTiers) Promote(tracker string) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	for _, tier := range tt.tiers {
+		for i, t := range tier {
+			if t == tracker {
+				copy(tier[1:i+1], tier[:i])
+				tier[0] = tracker
+				return
+			}
+		}
+	}
+}
+
+// Announce tries each tracker in tier order until one succeeds, then promotes it
+// and returns its URL. If every tracker fails, the individual errors are joined.
+func (tt *TrackerTiers) Announce(announce func(tracker string) error) (string, error) {
+	var errs []error
+	for _, tier := range tt.Tiers() {
+		for _, tracker := range tier {
+			if err := announce(tracker); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", tracker, err))
+				continue
+			}
+			tt.Promote(tracker)
+			return tracker, nil
+		}
+	}
+	if len(errs) == 0 {
+		return "", errors.New("torrentdata: no trackers available")
+	}
+	return "", fmt.Errorf("torrentdata: all trackers failed: %w", errors.Join(errs...))
+}
@@ -0,0 +1,158 @@
+package torrentdata
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func noShuffle(int, func(i, j int)) {}
+
+func reverseShuffle(n int, swap func(i, j int)) {
+	for i := 0; i < n/2; i++ {
+		swap(i, n-1-i)
+	}
+}
+
+func TestAnnounceTiers(t *testing.T) {
+	testCases := []struct {
+		name         string
+		metainfoFile MetainfoFile
+		expected     [][]string
+	}{
+		{
+			name:         "AnnounceOnly",
+			metainfoFile: MetainfoFile{Announce: "http://a"},
+			expected:     [][]string{{"http://a"}},
+		},
+		{
+			name: "AnnounceListTakesPrecedence",
+			metainfoFile: MetainfoFile{
+				Announce:     "http://a",
+				AnnounceList: [][]string{{"http://b", "udp://c"}, {}, {"", "http://d"}},
+			},
+			expected: [][]string{{"http://b", "udp://c"}, {"http://d"}},
+		},
+		{
+			name:         "NoTrackers",
+			metainfoFile: MetainfoFile{},
+			expected:     nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.metainfoFile.announceTiers(); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Unexpected tiers. Expected: %v, Got: %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestTrackerTiers(t *testing.T) {
+	t.Run("ShufflesWithinTiers", func(t *testing.T) {
+		tt := newTrackerTiers([][]string{{"a", "b", "c"}, {}, {"d", "e"}}, reverseShuffle)
+
+		expected := [][]string{{"c", "b", "a"}, {"e", "d"}}
+		if got := tt.Tiers(); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Unexpected tiers. Expected: %v, Got: %v", expected, got)
+		}
+	})
+
+	t.Run("DoesNotAliasInput", func(t *testing.T) {
+		input := [][]string{{"a", "b"}}
+		tt := newTrackerTiers(input, noShuffle)
+		tt.Promote("b")
+
+		if !reflect.DeepEqual(input, [][]string{{"a", "b"}}) {
+			t.Errorf("Input tiers were modified: %v", input)
+		}
+	})
+
+	t.Run("Promote", func(t *testing.T) {
+		tt := newTrackerTiers([][]string{{"a", "b", "c"}, {"d", "e"}}, noShuffle)
+		tt.Promote("c")
+		tt.Promote("e")
+
+		expected := [][]string{{"c", "a", "b"}, {"e", "d"}}
+		if got := tt.Tiers(); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Unexpected tiers. Expected: %v, Got: %v", expected, got)
+		}
+	})
+
+	t.Run("AnnounceFallsThroughTiers", func(t *testing.T) {
+		tt := newTrackerTiers([][]string{{"a", "b"}, {"c", "d"}}, noShuffle)
+
+		var tried []string
+		tracker, err := tt.Announce(func(tracker string) error {
+			tried = append(tried, tracker)
+			if tracker != "d" {
+				return errors.New("unreachable")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if tracker != "d" {
+			t.Errorf("Unexpected tracker: got %q, want %q", tracker, "d")
+		}
+		if expected := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(tried, expected) {
+			t.Errorf("Unexpected order. Expected: %v, Got: %v", expected, tried)
+		}
+
+		expected := [][]string{{"a", "b"}, {"d", "c"}}
+		if got := tt.Tiers(); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Unexpected tiers. Expected: %v, Got: %v", expected, got)
+		}
+	})
+
+	t.Run("AnnounceAllFail", func(t *testing.T) {
+		unreachable := errors.New("unreachable")
+		tt := newTrackerTiers([][]string{{"a"}, {"b"}}, noShuffle)
+
+		_, err := tt.Announce(func(string) error { return unreachable })
+		if err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+		if !errors.Is(err, unreachable) {
+			t.Errorf("Unexpected error: got %v, want wrapped %v", err, unreachable)
+		}
+	})
+
+	t.Run("AnnounceNoTrackers", func(t *testing.T) {
+		tt := NewTrackerTiers(nil)
+
+		_, err := tt.Announce(func(string) error { return nil })
+		if err == nil {
+			t.Error("Expected error, got nil")
+		} else if err.Error() != "torrentdata: no trackers available" {
+			t.Errorf("Unexpected error message: got %q, want %q", err.Error(), "torrentdata: no trackers available")
+		}
+	})
+}
+
+func TestOpenAnnounceList(t *testing.T) {
+	data := "d8:announce8:http://a13:announce-listll8:http://b7:udp://cel8:http://dee" +
+		"4:infod6:lengthi1e4:name1:a12:piece lengthi1e6:pieces20:abcdefghijklmnopqrstee"
+	path := filepath.Join(t.TempDir(), "tiers.torrent")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("Failed to write torrent: %v", err)
+	}
+
+	metainfoFile, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	torrentData, err := metainfoFile.toTorrentData()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := [][]string{{"http://b", "udp://c"}, {"http://d"}}
+	if !reflect.DeepEqual(torrentData.AnnounceList, expected) {
+		t.Errorf("Unexpected AnnounceList. Expected: %v, Got: %v", expected, torrentData.AnnounceList)
+	}
+}
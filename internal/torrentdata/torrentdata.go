@@ -1,39 +1,68 @@
 package torrentdata
 
 import (
-	"bytes"
 	"crypto/sha1"
 	"errors"
 	"fmt"
-	"net/url"
+	"io"
 	"os"
-	"strconv"
+	"sort"
 
-	"github.com/jackpal/bencode-go"
+	"github.com/mattheworford/gotorrent/internal/bencode"
 )
 
 // InfoDictionary represents the metadata of a torrent file.
 type InfoDictionary struct {
-	Pieces      string `bencode:"pieces"`
-	PieceLength int    `bencode:"piece length"`
-	Length      int    `bencode:"length"`
-	Name        string `bencode:"name"`
+	Pieces      string           `bencode:"pieces"`
+	PieceLength int              `bencode:"piece length"`
+	Length      int              `bencode:"length,omitempty"`
+	Name        string           `bencode:"name"`
+	Files       []FileDictionary `bencode:"files,omitempty"`
+	Private     int              `bencode:"private,omitempty"`
+	Source      string           `bencode:"source,omitempty"`
+	// Attr, SHA1 and SymlinkPath are the BEP 47 attributes of a single-file torrent.
+	Attr        string   `bencode:"attr,omitempty"`
+	SHA1        string   `bencode:"sha1,omitempty"`
+	SymlinkPath []string `bencode:"symlink path,omitempty"`
+	MetaVersion int      `bencode:"meta version,omitempty"`
+	// FileTree holds the v2 file tree as nested dictionaries.
+	FileTree map[string]interface{} `bencode:"file tree,omitempty"`
 }
 
 // MetainfoFile represents the top-level structure of a torrent file.
 type MetainfoFile struct {
-	Announce string         `bencode:"announce"`
-	Info     InfoDictionary `bencode:"info"`
+	Announce     string         `bencode:"announce,omitempty"`
+	AnnounceList [][]string     `bencode:"announce-list,omitempty"`
+	Comment      string         `bencode:"comment,omitempty"`
+	CreatedBy    string         `bencode:"created by,omitempty"`
+	CreationDate int64          `bencode:"creation date,omitempty"`
+	URLList      URLList        `bencode:"url-list,omitempty"`
+	Info         InfoDictionary `bencode:"info"`
+	// PieceLayers maps v2 pieces roots to the concatenated hashes of their piece layer.
+	PieceLayers map[string]string `bencode:"piece layers,omitempty"`
+	rawInfo     []byte            `bencode:"-"`
+	// extra holds the top-level keys that have no field, so that they survive a rewrite.
+	extra map[string]bencode.RawMessage `bencode:"-"`
 }
 
 // TorrentData represents the processed data extracted from a torrent file.
 type TorrentData struct {
-	Announce    string
-	InfoHash    [20]byte
-	PieceHashes [][20]byte
-	PieceLength int
-	Length      int
-	Name        string
+	Announce     string
+	AnnounceList [][]string
+	InfoHash     [20]byte
+	PieceHashes  [][20]byte
+	PieceLength  int
+	Length       int
+	Name         string
+	Files        []File
+	WebSeeds     []string
+	// Private marks a BEP 27 private torrent, whose peers may only come from its trackers.
+	Private     bool
+	MetaVersion int
+	InfoHashV2  [32]byte
+	// PieceLayers maps the pieces root of each v2 file larger than a piece to its piece hashes.
+	PieceLayers map[[32]byte][][32]byte
+	hybrid      bool
 }
 
 // Open parses the torrent file at the specified path and returns its metadata.
@@ -42,26 +71,76 @@ func Open(path string) (*MetainfoFile, error) {
 		return nil, errors.New("torrentdata: path cannot be empty")
 	}
 
-	file, err := os.Open(path)
+	f, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("torrentdata: failed to open file: %w", err)
 	}
-	defer file.Close()
+	defer f.Close()
+	return decodeMetainfo(f)
+}
+
+// decodeMetainfo decodes a bencoded metainfo file from r, keeping the original info
+// dictionary bytes and the top-level keys that have no field.
+func decodeMetainfo(r io.Reader) (*MetainfoFile, error) {
+	rawInfo, values, err := splitMetainfo(r)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
 
 	var metainfoFile MetainfoFile
-	if err := bencode.Unmarshal(file, &metainfoFile); err != nil {
-		return nil, fmt.Errorf("torrentdata: failed to parse torrent file: %w", err)
+	fields := metainfoFile.fields()
+	for _, key := range keys {
+		field, ok := fields[key]
+		if !ok {
+			if metainfoFile.extra == nil {
+				metainfoFile.extra = make(map[string]bencode.RawMessage)
+			}
+			metainfoFile.extra[key] = values[key]
+			continue
+		}
+		if err := bencode.Unmarshal(values[key], field); err != nil {
+			return nil, fmt.Errorf("torrentdata: failed to parse torrent file: field %q: %w", key, err)
+		}
 	}
+	metainfoFile.rawInfo = rawInfo
 	return &metainfoFile, nil
 }
 
+// fields maps the top-level keys that have a MetainfoFile field to that field.
+func (metainfoFile *MetainfoFile) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"announce":      &metainfoFile.Announce,
+		"announce-list": &metainfoFile.AnnounceList,
+		"comment":       &metainfoFile.Comment,
+		"created by":    &metainfoFile.CreatedBy,
+		"creation date": &metainfoFile.CreationDate,
+		"url-list":      &metainfoFile.URLList,
+		"info":          &metainfoFile.Info,
+		"piece layers":  &metainfoFile.PieceLayers,
+	}
+}
+
+// infoHash returns the SHA-1 hash of the original info dictionary bytes when they
+// were captured during decoding, falling back to re-encoding the InfoDictionary.
+func (metainfoFile *MetainfoFile) infoHash() ([20]byte, error) {
+	if metainfoFile.rawInfo != nil {
+		return sha1.Sum(metainfoFile.rawInfo), nil
+	}
+	return metainfoFile.Info.computeHash()
+}
+
 // computeHash computes the SHA-1 hash of the InfoDictionary.
 func (infoDict *InfoDictionary) computeHash() ([20]byte, error) {
-	var buf bytes.Buffer
-	if err := bencode.Marshal(&buf, *infoDict); err != nil {
+	encoded, err := bencode.Marshal(*infoDict)
+	if err != nil {
 		return [20]byte{}, fmt.Errorf("torrentdata: failed to marshal InfoDictionary: %w", err)
 	}
-	h := sha1.Sum(buf.Bytes())
+	h := sha1.Sum(encoded)
 	return h, nil
 }
 
@@ -83,7 +162,10 @@ func (i *InfoDictionary) decodePieces() ([][20]byte, error) {
 
 // toTorrentData converts MetainfoFile to TorrentData.
 func (metainfoFile *MetainfoFile) toTorrentData() (TorrentData, error) {
-	infoHash, err := metainfoFile.Info.computeHash()
+	if metainfoFile.Info.isV2() && metainfoFile.Info.Pieces == "" {
+		return metainfoFile.toV2TorrentData()
+	}
+	infoHash, err := metainfoFile.infoHash()
 	if err != nil {
 		return TorrentData{}, fmt.Errorf("torrentdata: failed to compute InfoHash: %w", err)
 	}
@@ -91,35 +173,27 @@ func (metainfoFile *MetainfoFile) toTorrentData() (TorrentData, error) {
 	if err != nil {
 		return TorrentData{}, fmt.Errorf("torrentdata: failed to split piece hashes: %w", err)
 	}
+	files, length, err := metainfoFile.Info.buildFiles()
+	if err != nil {
+		return TorrentData{}, fmt.Errorf("torrentdata: failed to lay out files: %w", err)
+	}
 	t := TorrentData{
-		Announce:    metainfoFile.Announce,
-		InfoHash:    infoHash,
-		PieceHashes: pieceHashes,
-		PieceLength: metainfoFile.Info.PieceLength,
-		Length:      metainfoFile.Info.Length,
-		Name:        metainfoFile.Info.Name,
+		Announce:     metainfoFile.Announce,
+		AnnounceList: metainfoFile.announceTiers(),
+		InfoHash:     infoHash,
+		PieceHashes:  pieceHashes,
+		PieceLength:  metainfoFile.Info.PieceLength,
+		Length:       length,
+		Name:         metainfoFile.Info.Name,
+		Files:        files,
+		WebSeeds:     metainfoFile.URLList,
+		Private:      metainfoFile.Info.Private == 1,
+		MetaVersion:  1,
 	}
-	return t, nil
-}
-
-func (t *TorrentData) buildTrackerURL(peerID [20]byte, port uint16) (string, error) {
-	baseURL, err := url.Parse(t.Announce)
-	if err != nil {
-		return "", fmt.Errorf("torrentdata: failed to parse announce URL: %v", err)
+	if metainfoFile.Info.isV2() {
+		if err := metainfoFile.addV2View(&t); err != nil {
+			return TorrentData{}, fmt.Errorf("torrentdata: invalid hybrid torrent: %w", err)
+		}
 	}
-
-	queryParams := url.Values{}
-	queryParams.Set("info_hash", string(t.InfoHash[:]))
-	peerIDString := string(peerID[:])
-	queryParams.Set("peer_id", peerIDString)
-	portString := strconv.Itoa(int(port))
-	queryParams.Set("port", portString)
-	queryParams.Set("uploaded", "0")
-	queryParams.Set("downloaded", "0")
-	queryParams.Set("compact", "1")
-	queryParams.Set("left", strconv.Itoa(t.Length))
-
-	baseURL.RawQuery = queryParams.Encode()
-
-	return baseURL.String(), nil
+	return t, nil
 }
@@ -3,9 +3,14 @@ package torrentdata
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+
+	"github.com/mattheworford/gotorrent/internal/bencode"
 )
 
 func TestOpen(t *testing.T) {
@@ -35,6 +40,41 @@ func TestOpen(t *testing.T) {
 		}
 	})
 
+	t.Run("TrailingData", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "trailing.torrent")
+		if err := os.WriteFile(path, []byte("d4:infod4:name1:aeei1e"), 0o644); err != nil {
+			t.Fatalf("Failed to write torrent: %v", err)
+		}
+		expected := "torrentdata: bencode: trailing data after value at offset 19"
+		if _, err := Open(path); err == nil || err.Error() != expected {
+			t.Errorf("Unexpected error: got %v, want %q", err, expected)
+		}
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		// Two strings of half the size limit, left as holes in a sparse file.
+		half := bencode.DefaultLimits.MaxSize / 2
+		header := []byte(fmt.Sprintf("%d:", half))
+		f, err := os.Create(filepath.Join(t.TempDir(), "large.torrent"))
+		if err != nil {
+			t.Fatalf("Failed to create torrent: %v", err)
+		}
+		defer f.Close()
+		f.WriteString("d1:a")
+		f.Write(header)
+		f.Seek(half, io.SeekCurrent)
+		f.WriteString("1:b")
+		f.Write(header)
+		f.Seek(half, io.SeekCurrent)
+		if _, err := f.WriteString("e"); err != nil {
+			t.Fatalf("Failed to write torrent: %v", err)
+		}
+
+		if _, err := Open(f.Name()); !errors.Is(err, bencode.ErrLimitExceeded) {
+			t.Errorf("Unexpected error: got %v, want %v", err, bencode.ErrLimitExceeded)
+		}
+	})
+
 	t.Run("InvalidFile", func(t *testing.T) {
 		_, err := Open("testdata/nonexistent.torrent")
 		if err == nil {
@@ -115,51 +155,3 @@ func TestToTorrentData(t *testing.T) {
 		}
 	})
 }
-func TestBuildTrackerURL(t *testing.T) {
-	t.Run("ValidAnnounceUrl", func(t *testing.T) {
-		torrentData := TorrentData{
-			Announce:    "http://tracker.example.com",
-			InfoHash:    [20]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20},
-			PieceHashes: [][20]byte{},
-			PieceLength: 256,
-			Length:      1024,
-			Name:        "example.torrent",
-		}
-
-		peerID := [20]byte{21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34, 35, 36, 37, 38, 39, 40}
-		port := uint16(8080)
-
-		expectedURL := "http://tracker.example.com?compact=1&downloaded=0&info_hash=%01%02%03%04%05%06%07%08%09%0A%0B%0C%0D%0E%0F%10%11%12%13%14&left=1024&peer_id=%15%16%17%18%19%1A%1B%1C%1D%1E%1F+%21%22%23%24%25%26%27%28&port=8080&uploaded=0"
-
-		url, err := torrentData.buildTrackerURL(peerID, port)
-		if err != nil {
-			t.Errorf("Unexpected error: %v", err)
-			return
-		}
-
-		if url != expectedURL {
-			t.Errorf("Unexpected URL. Expected: %s, Got: %s", expectedURL, url)
-		}
-	})
-
-	t.Run("MalformedAnnounceUrl", func(t *testing.T) {
-		torrentData := TorrentData{
-			Announce:    ":)",
-			InfoHash:    [20]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20},
-			PieceHashes: [][20]byte{},
-			PieceLength: 256,
-			Length:      1024,
-			Name:        "example.torrent",
-		}
-
-		peerID := [20]byte{21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34, 35, 36, 37, 38, 39, 40}
-		port := uint16(8080)
-
-		_, err := torrentData.buildTrackerURL(peerID, port)
-		if err == nil {
-			t.Error("Expected error, got nil")
-		} else if err.Error() != "torrentdata: failed to parse announce URL: parse \":)\": missing protocol scheme" {
-			t.Errorf("Unexpected error message: got %q, want %q", err.Error(), "torrentdata: failed to parse announce URL: parse \":)\": missing protocol scheme")
-		}
-	})
-}
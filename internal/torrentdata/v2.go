@@ -0,0 +1,223 @@
+package torrentdata
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/mattheworford/gotorrent/internal/bencode"
+)
+
+const (
+	// MetaVersion2 is the meta version of BitTorrent v2 (BEP 52) torrents.
+	MetaVersion2 = 2
+
+	fileTreeLeafKey = ""
+	piecesRootLen   = 32
+)
+
+// v2File represents a file entry of a v2 file tree.
+type v2File struct {
+	path        []string
+	length      int
+	piecesRoot  [32]byte
+	attr        string
+	symlinkPath []string
+}
+
+// isV2 tells if the info dictionary carries a v2 file tree.
+func (i *InfoDictionary) isV2() bool {
+	return i.MetaVersion == MetaVersion2
+}
+
+// walkFileTree flattens a v2 file tree into its files, in the order of their sorted paths.
+func walkFileTree(tree map[string]interface{}, parent []string) ([]v2File, error) {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var files []v2File
+	for _, name := range names {
+		node, ok := tree[name].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("torrentdata: file tree entry %q is not a dictionary", name)
+		}
+		if name == fileTreeLeafKey {
+			return nil, fmt.Errorf("torrentdata: unexpected file entry in directory %v", parent)
+		}
+		if !validPathComponent(name) {
+			return nil, fmt.Errorf("torrentdata: invalid name %q in directory %v", name, parent)
+		}
+		path := append(append([]string(nil), parent...), name)
+		if leaf, ok := node[fileTreeLeafKey].(map[string]interface{}); ok {
+			if len(node) != 1 {
+				return nil, fmt.Errorf("torrentdata: file %v also contains a directory", path)
+			}
+			f, err := parseFileTreeLeaf(leaf, path)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, f)
+			continue
+		}
+		children, err := walkFileTree(node, path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, children...)
+	}
+	return files, nil
+}
+
+func parseFileTreeLeaf(leaf map[string]interface{}, path []string) (v2File, error) {
+	f := v2File{path: path}
+	f.attr, _ = leaf["attr"].(string)
+	if target, ok := leaf["symlink path"].([]interface{}); ok {
+		for _, component := range target {
+			s, ok := component.(string)
+			if !ok {
+				return f, fmt.Errorf("torrentdata: file %v has malformed symlink path", path)
+			}
+			f.symlinkPath = append(f.symlinkPath, s)
+		}
+	}
+	switch length := leaf["length"].(type) {
+	case int64:
+		f.length = int(length)
+	default:
+		return f, fmt.Errorf("torrentdata: file %v has no length", path)
+	}
+	if f.length < 0 {
+		return f, fmt.Errorf("torrentdata: file %v has negative length", path)
+	}
+	if f.length == 0 {
+		return f, nil
+	}
+	root, ok := leaf["pieces root"].(string)
+	if !ok || len(root) != piecesRootLen {
+		return f, fmt.Errorf("torrentdata: file %v has malformed pieces root", path)
+	}
+	copy(f.piecesRoot[:], root)
+	return f, nil
+}
+
+// buildV2Files lays out the files of a v2 file tree and returns them with the total length.
+func (i *InfoDictionary) buildV2Files() ([]File, int, error) {
+	if len(i.FileTree) == 0 {
+		return nil, 0, errors.New("torrentdata: missing file tree")
+	}
+	if i.PieceLength < BlockSize || i.PieceLength&(i.PieceLength-1) != 0 {
+		return nil, 0, fmt.Errorf("torrentdata: piece length %d is not a power of two of at least %d", i.PieceLength, BlockSize)
+	}
+	if !validPathComponent(i.Name) {
+		return nil, 0, fmt.Errorf("torrentdata: invalid name %q", i.Name)
+	}
+	entries, err := walkFileTree(i.FileTree, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// A file tree holding a single file named after the torrent describes a single-file torrent.
+	root := []string{i.Name}
+	if len(entries) == 1 && len(entries[0].path) == 1 && entries[0].path[0] == i.Name {
+		root = nil
+	}
+
+	files := make([]File, len(entries))
+	offset := 0
+	for index, entry := range entries {
+		files[index] = File{
+			Path:       append(append([]string(nil), root...), entry.path...),
+			Length:     entry.length,
+			Offset:     offset,
+			PiecesRoot: entry.piecesRoot,
+		}
+		if err := files[index].setAttributes(entry.attr, "", entry.symlinkPath); err != nil {
+			return nil, 0, err
+		}
+		offset += entry.length
+	}
+	return files, offset, nil
+}
+
+// decodePieceLayers validates the piece layers of the given files against their pieces roots.
+func (metainfoFile *MetainfoFile) decodePieceLayers(files []File) (map[[32]byte][][32]byte, error) {
+	pieceLength := metainfoFile.Info.PieceLength
+	layers := make(map[[32]byte][][32]byte)
+	for _, f := range files {
+		if f.Length <= pieceLength {
+			continue
+		}
+		raw, ok := metainfoFile.PieceLayers[string(f.PiecesRoot[:])]
+		if !ok {
+			return nil, fmt.Errorf("torrentdata: missing piece layer for %v", f.Path)
+		}
+		numPieces := (f.Length + pieceLength - 1) / pieceLength
+		if len(raw) != numPieces*sha256.Size {
+			return nil, fmt.Errorf("torrentdata: malformed piece layer for %v", f.Path)
+		}
+		layer := make([][32]byte, numPieces)
+		for i := range layer {
+			copy(layer[i][:], raw[i*sha256.Size:(i+1)*sha256.Size])
+		}
+		if piecesRootFromLayer(layer, pieceLength) != f.PiecesRoot {
+			return nil, fmt.Errorf("torrentdata: piece layer for %v does not match its pieces root", f.Path)
+		}
+		layers[f.PiecesRoot] = layer
+	}
+	return layers, nil
+}
+
+// infoHashV2 returns the SHA-256 hash of the info dictionary.
+func (metainfoFile *MetainfoFile) infoHashV2() ([32]byte, error) {
+	if metainfoFile.rawInfo != nil {
+		return sha256.Sum256(metainfoFile.rawInfo), nil
+	}
+	encoded, err := bencode.Marshal(metainfoFile.Info)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("torrentdata: failed to marshal InfoDictionary: %w", err)
+	}
+	return sha256.Sum256(encoded), nil
+}
+
+// toV2TorrentData converts a v2 MetainfoFile to TorrentData.
+func (metainfoFile *MetainfoFile) toV2TorrentData() (TorrentData, error) {
+	files, length, err := metainfoFile.Info.buildV2Files()
+	if err != nil {
+		return TorrentData{}, fmt.Errorf("torrentdata: failed to lay out files: %w", err)
+	}
+	layers, err := metainfoFile.decodePieceLayers(files)
+	if err != nil {
+		return TorrentData{}, fmt.Errorf("torrentdata: failed to decode piece layers: %w", err)
+	}
+	infoHashV2, err := metainfoFile.infoHashV2()
+	if err != nil {
+		return TorrentData{}, fmt.Errorf("torrentdata: failed to compute InfoHashV2: %w", err)
+	}
+	t := TorrentData{
+		Announce:     metainfoFile.Announce,
+		AnnounceList: metainfoFile.announceTiers(),
+		InfoHashV2:   infoHashV2,
+		PieceLength:  metainfoFile.Info.PieceLength,
+		Length:       length,
+		Name:         metainfoFile.Info.Name,
+		Files:        files,
+		WebSeeds:     metainfoFile.URLList,
+		Private:      metainfoFile.Info.Private == 1,
+		MetaVersion:  MetaVersion2,
+		PieceLayers:  layers,
+	}
+	t.InfoHash = t.TruncatedInfoHashV2()
+	return t, nil
+}
+
+// TruncatedInfoHashV2 returns the v2 info hash truncated to 20 bytes, as used in
+// handshakes and tracker requests for v2 swarms.
+func (t *TorrentData) TruncatedInfoHashV2() [20]byte {
+	var h [20]byte
+	copy(h[:], t.InfoHashV2[:])
+	return h
+}
@@ -0,0 +1,240 @@
+package torrentdata
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mattheworford/gotorrent/internal/bencode"
+)
+
+const v2PieceLength = 2 * BlockSize
+
+func patternData(length, modulus int) []byte {
+	data := make([]byte, length)
+	for i := range data {
+		data[i] = byte(i % modulus)
+	}
+	return data
+}
+
+func fileTreeLeaf(data []byte) map[string]interface{} {
+	leaf := map[string]interface{}{"length": int64(len(data))}
+	if len(data) > 0 {
+		root := piecesRoot(data, v2PieceLength)
+		leaf["pieces root"] = string(root[:])
+	}
+	return map[string]interface{}{"": leaf}
+}
+
+func encodeLayer(layer [][32]byte) string {
+	var buf bytes.Buffer
+	for _, h := range layer {
+		buf.Write(h[:])
+	}
+	return buf.String()
+}
+
+func writeTorrent(t *testing.T, metainfo map[string]interface{}) string {
+	t.Helper()
+	data, err := bencode.Marshal(metainfo)
+	if err != nil {
+		t.Fatalf("Failed to marshal torrent: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "test.torrent")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("Failed to write torrent: %v", err)
+	}
+	return path
+}
+
+func TestPiecesRoot(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{
+			name:     "LargerThanPiece",
+			data:     patternData(120000, 251),
+			expected: "0b8a07bbc5b50574b8058e726b76fe209f047845726e651da508966c5dcd0e2a",
+		},
+		{
+			name:     "SmallerThanPiece",
+			data:     patternData(20000, 13),
+			expected: "64dcb8d89368b95fa87cd7626dcd80c0c5cfe2897455cf82e9938e6731ae4457",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := piecesRoot(tc.data, v2PieceLength)
+			if got := hex.EncodeToString(root[:]); got != tc.expected {
+				t.Errorf("Unexpected pieces root: got %s, want %s", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestOpenV2(t *testing.T) {
+	big := patternData(120000, 251)
+	small := patternData(20000, 13)
+	bigRoot := piecesRoot(big, v2PieceLength)
+	smallRoot := piecesRoot(small, v2PieceLength)
+
+	info := map[string]interface{}{
+		"meta version": int64(MetaVersion2),
+		"name":         "v2dir",
+		"piece length": int64(v2PieceLength),
+		"file tree": map[string]interface{}{
+			"big.bin": fileTreeLeaf(big),
+			"docs": map[string]interface{}{
+				"empty":     fileTreeLeaf(nil),
+				"small.txt": fileTreeLeaf(small),
+			},
+		},
+	}
+
+	t.Run("MultiFile", func(t *testing.T) {
+		path := writeTorrent(t, map[string]interface{}{
+			"announce":     "http://tracker.example/announce",
+			"info":         info,
+			"piece layers": map[string]interface{}{string(bigRoot[:]): encodeLayer(pieceLayer(big, v2PieceLength))},
+		})
+
+		metainfoFile, err := Open(path)
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		torrentData, err := metainfoFile.toTorrentData()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expectedFiles := []File{
+			{Path: []string{"v2dir", "big.bin"}, Length: 120000, Offset: 0, PiecesRoot: bigRoot},
+			{Path: []string{"v2dir", "docs", "empty"}, Length: 0, Offset: 120000},
+			{Path: []string{"v2dir", "docs", "small.txt"}, Length: 20000, Offset: 120000, PiecesRoot: smallRoot},
+		}
+		if !reflect.DeepEqual(torrentData.Files, expectedFiles) {
+			t.Errorf("Unexpected files. Expected: %v, Got: %v", expectedFiles, torrentData.Files)
+		}
+		if torrentData.MetaVersion != MetaVersion2 || !torrentData.IsMultiFile() || torrentData.Length != 140000 {
+			t.Errorf("Unexpected torrent data: %+v", torrentData)
+		}
+		if expected := sha256.Sum256(metainfoFile.rawInfo); torrentData.InfoHashV2 != expected {
+			t.Errorf("Unexpected InfoHashV2: got %x, want %x", torrentData.InfoHashV2, expected)
+		}
+		if !bytes.Equal(torrentData.InfoHash[:], torrentData.InfoHashV2[:20]) {
+			t.Errorf("Unexpected InfoHash: got %x, want truncated %x", torrentData.InfoHash, torrentData.InfoHashV2)
+		}
+		if layer := torrentData.PieceLayers[bigRoot]; len(layer) != 4 {
+			t.Errorf("Unexpected piece layer length: got %d, want %d", len(layer), 4)
+		}
+		if _, ok := torrentData.PieceLayers[smallRoot]; ok {
+			t.Error("Unexpected piece layer for a file smaller than a piece")
+		}
+	})
+
+	t.Run("SingleFile", func(t *testing.T) {
+		path := writeTorrent(t, map[string]interface{}{
+			"info": map[string]interface{}{
+				"meta version": int64(MetaVersion2),
+				"name":         "small.txt",
+				"piece length": int64(v2PieceLength),
+				"file tree":    map[string]interface{}{"small.txt": fileTreeLeaf(small)},
+			},
+		})
+
+		metainfoFile, err := Open(path)
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		torrentData, err := metainfoFile.toTorrentData()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if torrentData.IsMultiFile() || !reflect.DeepEqual(torrentData.Files[0].Path, []string{"small.txt"}) {
+			t.Errorf("Unexpected single-file layout: %v", torrentData.Files)
+		}
+	})
+
+	testCases := []struct {
+		name        string
+		pieceLayers map[string]interface{}
+		errMessage  string
+	}{
+		{
+			name:        "MissingPieceLayer",
+			pieceLayers: map[string]interface{}{},
+			errMessage:  "torrentdata: failed to decode piece layers: torrentdata: missing piece layer for [v2dir big.bin]",
+		},
+		{
+			name:        "MalformedPieceLayer",
+			pieceLayers: map[string]interface{}{string(bigRoot[:]): "short"},
+			errMessage:  "torrentdata: failed to decode piece layers: torrentdata: malformed piece layer for [v2dir big.bin]",
+		},
+		{
+			name:        "MismatchedPieceLayer",
+			pieceLayers: map[string]interface{}{string(bigRoot[:]): encodeLayer(make([][32]byte, 4))},
+			errMessage:  "torrentdata: failed to decode piece layers: torrentdata: piece layer for [v2dir big.bin] does not match its pieces root",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeTorrent(t, map[string]interface{}{"info": info, "piece layers": tc.pieceLayers})
+			metainfoFile, err := Open(path)
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+
+			_, err = metainfoFile.toTorrentData()
+			if err == nil {
+				t.Error("Expected error, got nil")
+			} else if err.Error() != tc.errMessage {
+				t.Errorf("Unexpected error message: got %q, want %q", err.Error(), tc.errMessage)
+			}
+		})
+	}
+}
+
+func TestOpenV2UnsafeNames(t *testing.T) {
+	leaf := fileTreeLeaf(patternData(100, 7))
+	testCases := []struct {
+		name     string
+		root     string
+		fileTree map[string]interface{}
+	}{
+		{"ParentDirectory", "v2dir", map[string]interface{}{"..": map[string]interface{}{"evil": leaf}}},
+		{"CurrentDirectory", "v2dir", map[string]interface{}{".": leaf}},
+		{"Slash", "v2dir", map[string]interface{}{"a/b": leaf}},
+		{"Backslash", "v2dir", map[string]interface{}{"a\\b": leaf}},
+		{"EmptyDirectory", "v2dir", map[string]interface{}{"docs": map[string]interface{}{"": map[string]interface{}{"x": leaf}}}},
+		{"UnsafeRootName", "..", map[string]interface{}{"a": leaf, "b": leaf}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeTorrent(t, map[string]interface{}{
+				"info": map[string]interface{}{
+					"meta version": int64(MetaVersion2),
+					"name":         tc.root,
+					"piece length": int64(v2PieceLength),
+					"file tree":    tc.fileTree,
+				},
+			})
+			metainfoFile, err := Open(path)
+			if err != nil {
+				return
+			}
+			if torrentData, err := metainfoFile.toTorrentData(); err == nil {
+				t.Errorf("Expected an error, got files %v", torrentData.Files)
+			}
+		})
+	}
+}
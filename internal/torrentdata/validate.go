@@ -0,0 +1,502 @@
+package torrentdata
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/mattheworford/gotorrent/internal/bencode"
+)
+
+// Severity represents how serious a validation issue is.
+type Severity uint8
+
+const (
+	SeverityWarning Severity = iota // 0
+	SeverityError                   // 1
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// MarshalText encodes the severity by name.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Issue represents a single problem found while validating a torrent.
+type Issue struct {
+	Severity Severity `json:"severity"`
+	Field    string   `json:"field"`
+	Message  string   `json:"message"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Severity, i.Field, i.Message)
+}
+
+// Report represents the outcome of validating a torrent.
+type Report struct {
+	Issues []Issue `json:"issues"`
+}
+
+// Valid tells if the report contains no errors. Warnings do not make a torrent invalid.
+func (r *Report) Valid() bool {
+	return len(r.Errors()) == 0
+}
+
+// Errors returns the issues with error severity.
+func (r *Report) Errors() []Issue {
+	return r.filter(SeverityError)
+}
+
+// Warnings returns the issues with warning severity.
+func (r *Report) Warnings() []Issue {
+	return r.filter(SeverityWarning)
+}
+
+func (r *Report) filter(severity Severity) []Issue {
+	var issues []Issue
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+func (r *Report) errorf(field, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, Issue{Severity: SeverityError, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *Report) warnf(field, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, Issue{Severity: SeverityWarning, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate lints a bencoded metainfo file and reports every problem found, rather than
+// stopping at the first one as decoding does.
+func Validate(data []byte) *Report {
+	report := &Report{Issues: []Issue{}}
+	var decoded interface{}
+	if err := bencode.Unmarshal(data, &decoded); err != nil {
+		report.errorf("metainfo", "not valid bencode: %v", err)
+		return report
+	}
+	strict := bencode.NewDecoder(bytes.NewReader(data))
+	strict.Strict = true
+	if err := strict.Decode(new(bencode.RawMessage)); err != nil {
+		report.warnf("metainfo", "not in canonical form: %v", err)
+	}
+	metainfo, ok := decoded.(map[string]interface{})
+	if !ok {
+		report.errorf("metainfo", "expected a dictionary, got %s", typeName(decoded))
+		return report
+	}
+
+	report.validateTrackers(metainfo)
+	report.checkType(metainfo, "comment", "", isString)
+	report.checkType(metainfo, "created by", "", isString)
+	report.checkType(metainfo, "creation date", "", isInt)
+
+	info, ok := metainfo["info"].(map[string]interface{})
+	if !ok {
+		if _, present := metainfo["info"]; present {
+			report.errorf("info", "expected a dictionary, got %s", typeName(metainfo["info"]))
+		} else {
+			report.errorf("info", "missing")
+		}
+		return report
+	}
+	report.validateInfo(info)
+
+	// Decoding applies the remaining structural checks, such as v2 file trees and piece layers.
+	if report.Valid() {
+		metainfoFile, err := decodeMetainfo(bytes.NewReader(data))
+		if err == nil {
+			_, err = metainfoFile.toTorrentData()
+		}
+		if err != nil {
+			report.errorf("metainfo", "%v", err)
+		}
+	}
+	return report
+}
+
+func (r *Report) validateTrackers(metainfo map[string]interface{}) {
+	if r.checkType(metainfo, "announce", "", isString) {
+		r.checkURL("announce", metainfo["announce"].(string), "http", "https", "udp")
+	}
+	if list, ok := metainfo["announce-list"]; ok {
+		tiers, ok := list.([]interface{})
+		if !ok {
+			r.errorf("announce-list", "expected a list, got %s", typeName(list))
+		}
+		for i, tier := range tiers {
+			field := fmt.Sprintf("announce-list[%d]", i)
+			trackers, ok := tier.([]interface{})
+			if !ok {
+				r.errorf(field, "expected a list, got %s", typeName(tier))
+				continue
+			}
+			for j, tracker := range trackers {
+				trackerField := fmt.Sprintf("%s[%d]", field, j)
+				if s, ok := tracker.(string); ok {
+					r.checkURL(trackerField, s, "http", "https", "udp")
+				} else {
+					r.errorf(trackerField, "expected a string, got %s", typeName(tracker))
+				}
+			}
+		}
+	}
+	if _, hasAnnounce := metainfo["announce"]; !hasAnnounce {
+		if _, hasList := metainfo["announce-list"]; !hasList {
+			r.warnf("announce", "no trackers; peers can only be found through DHT or web seeds")
+		}
+	}
+
+	switch seeds := metainfo["url-list"].(type) {
+	case nil:
+	case string:
+		r.checkURL("url-list", seeds, "http", "https", "ftp")
+	case []interface{}:
+		for i, seed := range seeds {
+			field := fmt.Sprintf("url-list[%d]", i)
+			if s, ok := seed.(string); ok {
+				r.checkURL(field, s, "http", "https", "ftp")
+			} else {
+				r.errorf(field, "expected a string, got %s", typeName(seed))
+			}
+		}
+	default:
+		r.errorf("url-list", "expected a string or list, got %s", typeName(seeds))
+	}
+}
+
+func (r *Report) validateInfo(info map[string]interface{}) {
+	if r.checkType(info, "name", "info.", isString) {
+		r.checkPathComponent("info.name", info["name"].(string))
+	} else if _, ok := info["name"]; !ok {
+		r.errorf("info.name", "missing")
+	}
+	r.checkType(info, "source", "info.", isString)
+	if r.checkType(info, "private", "info.", isInt) {
+		if private, _ := asInt(info["private"]); private != 0 && private != 1 {
+			r.warnf("info.private", "expected 0 or 1, got %d", private)
+		}
+	}
+
+	isV2 := false
+	if r.checkType(info, "meta version", "info.", isInt) {
+		version, _ := asInt(info["meta version"])
+		switch version {
+		case 1:
+		case MetaVersion2:
+			isV2 = true
+		default:
+			r.errorf("info.meta version", "unsupported version %d", version)
+		}
+	}
+
+	pieceLength := int64(0)
+	if r.checkType(info, "piece length", "info.", isInt) {
+		pieceLength, _ = asInt(info["piece length"])
+		switch {
+		case pieceLength <= 0:
+			r.errorf("info.piece length", "must be positive, got %d", pieceLength)
+			pieceLength = 0
+		case pieceLength&(pieceLength-1) != 0:
+			if isV2 {
+				r.errorf("info.piece length", "%d is not a power of two", pieceLength)
+			} else {
+				r.warnf("info.piece length", "%d is not a power of two", pieceLength)
+			}
+		case pieceLength < BlockSize:
+			if isV2 {
+				r.errorf("info.piece length", "%d is smaller than %d", pieceLength, BlockSize)
+			} else {
+				r.warnf("info.piece length", "%d is smaller than %d", pieceLength, BlockSize)
+			}
+		}
+	} else if _, ok := info["piece length"]; !ok {
+		r.errorf("info.piece length", "missing")
+	}
+
+	_, hasPieces := info["pieces"]
+	_, hasLength := info["length"]
+	_, hasFiles := info["files"]
+	if isV2 {
+		r.validateFileTree(info)
+		if !hasPieces && !hasLength && !hasFiles {
+			// A v2-only torrent, with no v1 layout to check.
+			return
+		}
+	}
+	total, layoutOK := r.validateLayout(info)
+	if !hasPieces {
+		r.errorf("info.pieces", "missing")
+		return
+	}
+	if !r.checkType(info, "pieces", "info.", isString) || !layoutOK || pieceLength == 0 {
+		return
+	}
+	pieces := info["pieces"].(string)
+	if len(pieces)%20 != 0 {
+		r.errorf("info.pieces", "length %d is not a multiple of 20", len(pieces))
+		return
+	}
+	expected := (total + pieceLength - 1) / pieceLength
+	if int64(len(pieces)/20) != expected {
+		r.errorf("info.pieces", "expected %d piece hashes for %d bytes with piece length %d, got %d", expected, total, pieceLength, len(pieces)/20)
+	}
+}
+
+// validateLayout checks the v1 length or files list and returns the total length.
+func (r *Report) validateLayout(info map[string]interface{}) (int64, bool) {
+	_, hasLength := info["length"]
+	rawFiles, hasFiles := info["files"]
+	switch {
+	case hasLength && hasFiles:
+		r.errorf("info", "both length and files are present")
+		return 0, false
+	case !hasLength && !hasFiles:
+		r.errorf("info", "neither length nor files is present")
+		return 0, false
+	case hasLength:
+		if !r.checkType(info, "length", "info.", isInt) {
+			return 0, false
+		}
+		length, _ := asInt(info["length"])
+		if length < 0 {
+			r.errorf("info.length", "must not be negative, got %d", length)
+			return 0, false
+		}
+		return length, true
+	}
+
+	files, ok := rawFiles.([]interface{})
+	if !ok {
+		r.errorf("info.files", "expected a list, got %s", typeName(rawFiles))
+		return 0, false
+	}
+	if len(files) == 0 {
+		r.errorf("info.files", "empty")
+		return 0, false
+	}
+	valid := true
+	total := int64(0)
+	seen := make(map[string]string)
+	for i, rawFile := range files {
+		field := fmt.Sprintf("info.files[%d]", i)
+		file, ok := rawFile.(map[string]interface{})
+		if !ok {
+			r.errorf(field, "expected a dictionary, got %s", typeName(rawFile))
+			valid = false
+			continue
+		}
+		if r.checkType(file, "length", field+".", isInt) {
+			length, _ := asInt(file["length"])
+			if length < 0 {
+				r.errorf(field+".length", "must not be negative, got %d", length)
+				valid = false
+			}
+			total += length
+		} else {
+			if _, ok := file["length"]; !ok {
+				r.errorf(field+".length", "missing")
+			}
+			valid = false
+		}
+		r.checkType(file, "attr", field+".", isString)
+
+		path, ok := r.validatePath(file["path"], field+".path")
+		if !ok {
+			continue
+		}
+		r.checkDuplicate(seen, field+".path", path)
+	}
+	return total, valid
+}
+
+// validateFileTree checks the names in a v2 file tree as validateLayout checks the paths
+// of the files list.
+func (r *Report) validateFileTree(info map[string]interface{}) {
+	rawTree, ok := info["file tree"]
+	if !ok {
+		r.errorf("info.file tree", "missing")
+		return
+	}
+	tree, ok := rawTree.(map[string]interface{})
+	if !ok {
+		r.errorf("info.file tree", "expected a dictionary, got %s", typeName(rawTree))
+		return
+	}
+	if len(tree) == 0 {
+		r.errorf("info.file tree", "empty")
+		return
+	}
+	r.validateFileTreeDir(tree, "info.file tree", nil, make(map[string]string))
+}
+
+func (r *Report) validateFileTreeDir(dir map[string]interface{}, field string, parent []string, seen map[string]string) {
+	names := make([]string, 0, len(dir))
+	for name := range dir {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		entryField := fmt.Sprintf("%s[%q]", field, name)
+		if !r.checkPathComponent(entryField, name) {
+			continue
+		}
+		node, ok := dir[name].(map[string]interface{})
+		if !ok {
+			r.errorf(entryField, "expected a dictionary, got %s", typeName(dir[name]))
+			continue
+		}
+		path := append(append([]string(nil), parent...), name)
+		if _, isFile := node[fileTreeLeafKey]; !isFile {
+			r.validateFileTreeDir(node, entryField, path, seen)
+			continue
+		}
+		if len(node) != 1 {
+			r.errorf(entryField, "file also contains a directory")
+		}
+		r.checkDuplicate(seen, entryField, path)
+	}
+}
+
+// checkDuplicate records a file path in seen, which maps each path seen so far, folded to
+// lower case, to its original form. Paths seen before are errors; paths differing only in
+// case are warnings, as they collide on case-insensitive file systems.
+func (r *Report) checkDuplicate(seen map[string]string, field string, path []string) {
+	joined := strings.Join(path, "/")
+	folded := strings.ToLower(joined)
+	if previous, ok := seen[folded]; ok {
+		if previous == joined {
+			r.errorf(field, "duplicate path %q", joined)
+		} else {
+			r.warnf(field, "path %q only differs in case from %q", joined, previous)
+		}
+		return
+	}
+	seen[folded] = joined
+}
+
+func (r *Report) validatePath(rawPath interface{}, field string) ([]string, bool) {
+	if rawPath == nil {
+		r.errorf(field, "missing")
+		return nil, false
+	}
+	components, ok := rawPath.([]interface{})
+	if !ok {
+		r.errorf(field, "expected a list, got %s", typeName(rawPath))
+		return nil, false
+	}
+	if len(components) == 0 {
+		r.errorf(field, "empty path")
+		return nil, false
+	}
+	path := make([]string, len(components))
+	valid := true
+	for i, component := range components {
+		s, ok := component.(string)
+		if !ok {
+			r.errorf(fmt.Sprintf("%s[%d]", field, i), "expected a string, got %s", typeName(component))
+			valid = false
+			continue
+		}
+		if !r.checkPathComponent(fmt.Sprintf("%s[%d]", field, i), s) {
+			valid = false
+		}
+		path[i] = s
+	}
+	return path, valid
+}
+
+// checkPathComponent rejects path components that are empty or could escape the download directory.
+func (r *Report) checkPathComponent(field, component string) bool {
+	switch {
+	case component == "":
+		r.errorf(field, "empty path component")
+	case component == "." || component == "..":
+		r.errorf(field, "path traversal component %q", component)
+	case strings.ContainsAny(component, "/\\"):
+		r.errorf(field, "path separator in component %q", component)
+	case strings.ContainsRune(component, 0):
+		r.errorf(field, "NUL byte in component %q", component)
+	default:
+		return true
+	}
+	return false
+}
+
+func (r *Report) checkURL(field, rawURL string, schemes ...string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		r.errorf(field, "invalid URL %q: %v", rawURL, err)
+		return
+	}
+	for _, scheme := range schemes {
+		if strings.EqualFold(u.Scheme, scheme) {
+			if u.Host == "" {
+				r.errorf(field, "URL %q has no host", rawURL)
+			}
+			return
+		}
+	}
+	r.warnf(field, "unsupported scheme %q in URL %q", u.Scheme, rawURL)
+}
+
+// checkType reports a key holding a value of the wrong type, and tells if the key is
+// present with the expected type.
+func (r *Report) checkType(dict map[string]interface{}, key, prefix string, check func(interface{}) bool) bool {
+	value, ok := dict[key]
+	if !ok {
+		return false
+	}
+	if !check(value) {
+		expected := "a string"
+		if check(int64(0)) {
+			expected = "an integer"
+		}
+		r.errorf(prefix+key, "expected %s, got %s", expected, typeName(value))
+		return false
+	}
+	return true
+}
+
+func isString(v interface{}) bool {
+	_, ok := v.(string)
+	return ok
+}
+
+func isInt(v interface{}) bool {
+	_, ok := asInt(v)
+	return ok
+}
+
+func asInt(v interface{}) (int64, bool) {
+	n, ok := v.(int64)
+	return n, ok
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "a string"
+	case int64:
+		return "an integer"
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "a dictionary"
+	}
+	return fmt.Sprintf("%T", v)
+}
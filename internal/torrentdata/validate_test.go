@@ -0,0 +1,149 @@
+package torrentdata
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("ValidFile", func(t *testing.T) {
+		data, err := os.ReadFile("../../test/data/archlinux-2019.12.01-x86_64.iso.torrent")
+		if err != nil {
+			t.Fatalf("Failed to read torrent: %v", err)
+		}
+
+		report := Validate(data)
+		if !report.Valid() || len(report.Issues) != 0 {
+			t.Errorf("Unexpected issues: %v", report.Issues)
+		}
+	})
+
+	pieces := "20:abcdefghijklmnopqrst"
+	testCases := []struct {
+		name     string
+		data     string
+		expected Issue
+	}{
+		{
+			name:     "NotBencode",
+			data:     "d8:announce",
+			expected: Issue{SeverityError, "metainfo", "not valid bencode: unexpected EOF"},
+		},
+		{
+			name:     "MissingInfo",
+			data:     "d8:announce8:http://ae",
+			expected: Issue{SeverityError, "info", "missing"},
+		},
+		{
+			name:     "WrongKeyType",
+			data:     "d8:announcei1e4:infod6:lengthi1e4:name1:a12:piece lengthi16384e6:pieces" + pieces + "ee",
+			expected: Issue{SeverityError, "announce", "expected a string, got an integer"},
+		},
+		{
+			name:     "UnsupportedScheme",
+			data:     "d8:announce9:ws://a/ws4:infod6:lengthi1e4:name1:a12:piece lengthi16384e6:pieces" + pieces + "ee",
+			expected: Issue{SeverityWarning, "announce", "unsupported scheme \"ws\" in URL \"ws://a/ws\""},
+		},
+		{
+			name:     "PieceCountMismatch",
+			data:     "d8:announce8:http://a4:infod6:lengthi40000e4:name1:a12:piece lengthi16384e6:pieces" + pieces + "ee",
+			expected: Issue{SeverityError, "info.pieces", "expected 3 piece hashes for 40000 bytes with piece length 16384, got 1"},
+		},
+		{
+			name:     "NonPowerOfTwoPieceLength",
+			data:     "d8:announce8:http://a4:infod6:lengthi1e4:name1:a12:piece lengthi20000e6:pieces" + pieces + "ee",
+			expected: Issue{SeverityWarning, "info.piece length", "20000 is not a power of two"},
+		},
+		{
+			name:     "MalformedPieces",
+			data:     "d8:announce8:http://a4:infod6:lengthi1e4:name1:a12:piece lengthi16384e6:pieces3:abcee",
+			expected: Issue{SeverityError, "info.pieces", "length 3 is not a multiple of 20"},
+		},
+		{
+			name:     "PathTraversal",
+			data:     "d8:announce8:http://a4:infod5:filesld6:lengthi1e4:pathl2:..6:passwdeee4:name1:a12:piece lengthi16384e6:pieces" + pieces + "ee",
+			expected: Issue{SeverityError, "info.files[0].path[0]", "path traversal component \"..\""},
+		},
+		{
+			name:     "SeparatorInComponent",
+			data:     "d8:announce8:http://a4:infod5:filesld6:lengthi1e4:pathl7:etc/abceee4:name1:a12:piece lengthi16384e6:pieces" + pieces + "ee",
+			expected: Issue{SeverityError, "info.files[0].path[0]", "path separator in component \"etc/abc\""},
+		},
+		{
+			name:     "EmptyPath",
+			data:     "d8:announce8:http://a4:infod5:filesld6:lengthi1e4:pathleee4:name1:a12:piece lengthi16384e6:pieces" + pieces + "ee",
+			expected: Issue{SeverityError, "info.files[0].path", "empty path"},
+		},
+		{
+			name:     "DuplicatePath",
+			data:     "d8:announce8:http://a4:infod5:filesld6:lengthi1e4:pathl1:beed6:lengthi1e4:pathl1:beee4:name1:a12:piece lengthi16384e6:pieces" + pieces + "ee",
+			expected: Issue{SeverityError, "info.files[1].path", "duplicate path \"b\""},
+		},
+		{
+			name:     "FileTreeTraversal",
+			data:     "d8:announce8:http://a4:infod9:file treed2:..d6:passwdd0:d6:lengthi1eeeee12:meta versioni2e4:name1:a12:piece lengthi16384eee",
+			expected: Issue{SeverityError, "info.file tree[\"..\"]", "path traversal component \"..\""},
+		},
+		{
+			name:     "FileTreeEmptyName",
+			data:     "d8:announce8:http://a4:infod9:file treed0:d0:d6:lengthi1eeee12:meta versioni2e4:name1:a12:piece lengthi16384eee",
+			expected: Issue{SeverityError, "info.file tree[\"\"]", "empty path component"},
+		},
+		{
+			name:     "FileTreeCaseCollision",
+			data:     "d8:announce8:http://a4:infod9:file treed1:Ad0:d6:lengthi0eee1:ad0:d6:lengthi0eeee12:meta versioni2e4:name1:a12:piece lengthi16384eee",
+			expected: Issue{SeverityWarning, "info.file tree[\"a\"]", "path \"a\" only differs in case from \"A\""},
+		},
+		{
+			name:     "LengthAndFiles",
+			data:     "d8:announce8:http://a4:infod5:filesld6:lengthi1e4:pathl1:beee6:lengthi1e4:name1:a12:piece lengthi16384e6:pieces" + pieces + "ee",
+			expected: Issue{SeverityError, "info", "both length and files are present"},
+		},
+		{
+			name:     "NotCanonical",
+			data:     "d4:infod6:lengthi1e4:name1:a12:piece lengthi16384e6:pieces" + pieces + "e8:announce8:http://ae",
+			expected: Issue{SeverityWarning, "metainfo", "not in canonical form: bencode: dictionary key \"announce\" is not sorted or repeated at offset 82"},
+		},
+		{
+			name:     "NoTrackers",
+			data:     "d4:infod6:lengthi1e4:name1:a12:piece lengthi16384e6:pieces" + pieces + "ee",
+			expected: Issue{SeverityWarning, "announce", "no trackers; peers can only be found through DHT or web seeds"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			report := Validate([]byte(tc.data))
+			found := false
+			for _, issue := range report.Issues {
+				if issue == tc.expected {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Expected issue %q, got %v", tc.expected, report.Issues)
+			}
+			if report.Valid() != (tc.expected.Severity == SeverityWarning) {
+				t.Errorf("Unexpected validity %v for issues %v", report.Valid(), report.Issues)
+			}
+		})
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	report := Report{Issues: []Issue{{SeverityError, "info.pieces", "missing"}, {SeverityWarning, "announce", "no trackers"}}}
+
+	encoded, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := `{"issues":[{"severity":"error","field":"info.pieces","message":"missing"},{"severity":"warning","field":"announce","message":"no trackers"}]}`
+	if string(encoded) != expected {
+		t.Errorf("Unexpected JSON: got %s, want %s", encoded, expected)
+	}
+	if len(report.Errors()) != 1 || len(report.Warnings()) != 1 {
+		t.Errorf("Unexpected split: %d errors, %d warnings", len(report.Errors()), len(report.Warnings()))
+	}
+}
@@ -0,0 +1,49 @@
+package torrentdata
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+)
+
+// VerifyPiece checks data against the v1 SHA-1 hash of the piece at the given index.
+func (t *TorrentData) VerifyPiece(index int, data []byte) (bool, error) {
+	size, err := t.PieceSize(index)
+	if err != nil {
+		return false, err
+	}
+	if len(data) != size {
+		return false, nil
+	}
+	return sha1.Sum(data) == t.PieceHashes[index], nil
+}
+
+// VerifyPieceV2 checks data against the v2 merkle tree of the file at fileIndex, where
+// pieceIndex counts pieces from the start of that file.
+func (t *TorrentData) VerifyPieceV2(fileIndex, pieceIndex int, data []byte) (bool, error) {
+	if t.MetaVersion != MetaVersion2 {
+		return false, errors.New("torrentdata: torrent has no v2 metadata")
+	}
+	if fileIndex < 0 || fileIndex >= len(t.Files) {
+		return false, fmt.Errorf("torrentdata: file index %d out of range", fileIndex)
+	}
+	f := t.Files[fileIndex]
+	if f.Padding || f.Length == 0 {
+		return false, fmt.Errorf("torrentdata: file %v has no v2 hashes", f.Path)
+	}
+	if f.Length <= t.PieceLength {
+		if pieceIndex != 0 {
+			return false, fmt.Errorf("torrentdata: piece index %d out of range", pieceIndex)
+		}
+		return len(data) == f.Length && piecesRoot(data, t.PieceLength) == f.PiecesRoot, nil
+	}
+	layer := t.PieceLayers[f.PiecesRoot]
+	if pieceIndex < 0 || pieceIndex >= len(layer) {
+		return false, fmt.Errorf("torrentdata: piece index %d out of range", pieceIndex)
+	}
+	expectedSize := min(t.PieceLength, f.Length-pieceIndex*t.PieceLength)
+	if len(data) != expectedSize {
+		return false, nil
+	}
+	return pieceRoot(hashBlocks(data), t.PieceLength/BlockSize) == layer[pieceIndex], nil
+}
@@ -0,0 +1,76 @@
+package torrentdata
+
+import "testing"
+
+func TestVerifyPiece(t *testing.T) {
+	f := newHybridFixture()
+	_, torrentData, err := f.open(t)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	firstPiece := append(append([]byte{}, f.a...), make([]byte, v2PieceLength-len(f.a))...)
+	lastPiece := f.b[3*v2PieceLength:]
+
+	t.Run("V1", func(t *testing.T) {
+		testCases := []struct {
+			name     string
+			index    int
+			data     []byte
+			expected bool
+		}{
+			{"FirstPieceWithPadding", 0, firstPiece, true},
+			{"ShortLastPiece", 4, lastPiece, true},
+			{"CorruptPiece", 0, make([]byte, v2PieceLength), false},
+			{"WrongSize", 4, f.b[:10], false},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				ok, err := torrentData.VerifyPiece(tc.index, tc.data)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if ok != tc.expected {
+					t.Errorf("Expected VerifyPiece(%d) to be %v, but got %v", tc.index, tc.expected, ok)
+				}
+			})
+		}
+	})
+
+	t.Run("V2", func(t *testing.T) {
+		testCases := []struct {
+			name       string
+			fileIndex  int
+			pieceIndex int
+			data       []byte
+			expected   bool
+			expectErr  bool
+		}{
+			{"SmallFile", 0, 0, f.a, true, false},
+			{"PieceFromLayer", 2, 1, f.b[v2PieceLength : 2*v2PieceLength], true, false},
+			{"ShortLastPieceFromLayer", 2, 3, lastPiece, true, false},
+			{"CorruptPiece", 2, 1, f.b[:v2PieceLength], false, false},
+			{"PaddingFile", 1, 0, nil, false, true},
+			{"PieceOutOfRange", 2, 4, nil, false, true},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				ok, err := torrentData.VerifyPieceV2(tc.fileIndex, tc.pieceIndex, tc.data)
+				if tc.expectErr {
+					if err == nil {
+						t.Error("Expected error, got nil")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if ok != tc.expected {
+					t.Errorf("Expected VerifyPieceV2(%d, %d) to be %v, but got %v", tc.fileIndex, tc.pieceIndex, tc.expected, ok)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,23 @@
+package torrentdata
+
+import "github.com/mattheworford/gotorrent/internal/bencode"
+
+// URLList is the BEP 19 list of web seed URLs. Some torrents give a single URL as a string
+// rather than a list, which decodes to a list of that URL.
+type URLList []string
+
+// UnmarshalBencode decodes a url-list given either as a list or a single string.
+func (l *URLList) UnmarshalBencode(data []byte) error {
+	if len(data) > 0 && data[0] >= '0' && data[0] <= '9' {
+		var url string
+		if err := bencode.Unmarshal(data, &url); err != nil {
+			return err
+		}
+		*l = nil
+		if url != "" {
+			*l = URLList{url}
+		}
+		return nil
+	}
+	return bencode.Unmarshal(data, (*[]string)(l))
+}
@@ -0,0 +1,54 @@
+package torrentdata
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOpenURLList(t *testing.T) {
+	info := "4:infod6:lengthi1e4:name1:a12:piece lengthi1e6:pieces20:abcdefghijklmnopqrste"
+
+	testCases := []struct {
+		name     string
+		data     string
+		expected []string
+	}{
+		{
+			name:     "List",
+			data:     "d" + info + "8:url-listl17:http://a.example/17:http://b.example/ee",
+			expected: []string{"http://a.example/", "http://b.example/"},
+		},
+		{
+			name:     "SingleString",
+			data:     "d" + info + "8:url-list17:http://a.example/e",
+			expected: []string{"http://a.example/"},
+		},
+		{
+			name:     "Absent",
+			data:     "d" + info + "e",
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "webseeds.torrent")
+			if err := os.WriteFile(path, []byte(tc.data), 0o644); err != nil {
+				t.Fatalf("Failed to write torrent: %v", err)
+			}
+			metainfoFile, err := Open(path)
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			torrentData, err := metainfoFile.toTorrentData()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(torrentData.WebSeeds, tc.expected) {
+				t.Errorf("Unexpected WebSeeds. Expected: %v, Got: %v", tc.expected, torrentData.WebSeeds)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package tracker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/peer"
+	"github.com/mattheworford/gotorrent/internal/torrentdata"
+)
+
+const (
+	defaultInterval      = 30 * time.Minute
+	defaultRetryInterval = time.Minute
+	defaultNumWant       = 50
+	stopTimeout          = 10 * time.Second
+)
+
+// Announcer keeps a tracker informed about a download for as long as it runs: it sends
+// started on the first announce, periodic updates at the tracker's interval, completed
+// when the last piece verifies, and stopped on shutdown.
+type Announcer struct {
+	Tracker  Tracker
+	InfoHash [20]byte
+	PeerID   [20]byte
+	Port     uint16
+	NumWant  int
+	Stats    *Stats
+	// RetryInterval is how long to wait before retrying a failed announce. Defaults to a minute.
+	RetryInterval time.Duration
+	// OnPeers, if set, receives the peers returned by each announce.
+	OnPeers func([]peer.ConnectionInfo)
+	// OnError, if set, receives announce failures and tracker warnings.
+	OnError func(error)
+
+	key       uint32
+	trackerID string
+}
+
+// NewAnnouncer creates an Announcer reporting the download of a torrent to a tracker.
+func NewAnnouncer(tracker Tracker, torrent *torrentdata.TorrentData, peerID [20]byte, port uint16, stats *Stats) *Announcer {
+	var key [4]byte
+	rand.Read(key[:])
+	return &Announcer{
+		Tracker:       tracker,
+		InfoHash:      torrent.InfoHash,
+		PeerID:        peerID,
+		Port:          port,
+		NumWant:       defaultNumWant,
+		Stats:         stats,
+		RetryInterval: defaultRetryInterval,
+		key:           binary.BigEndian.Uint32(key[:]) | 1,
+	}
+}
+
+// Run announces until ctx is cancelled, then sends a stopped announce if the tracker knows
+// about the download and returns ctx.Err(). A failed announce is retried with the same event.
+func (a *Announcer) Run(ctx context.Context) error {
+	var completed <-chan struct{}
+	if a.Stats.Left() > 0 {
+		completed = a.Stats.Complete()
+	}
+
+	event := EventStarted
+	for {
+		wait, err := a.announce(ctx, event)
+		if err == nil {
+			event = EventNone
+		}
+
+		// Completion is only reported once the tracker has seen the started event.
+		var completedNow <-chan struct{}
+		if event == EventNone {
+			completedNow = completed
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if event != EventStarted {
+				stopCtx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+				a.announce(stopCtx, EventStopped)
+				cancel()
+			}
+			return ctx.Err()
+		case <-completedNow:
+			timer.Stop()
+			completed = nil
+			event = EventCompleted
+		case <-timer.C:
+		}
+	}
+}
+
+// announce sends one announce and returns how long to wait before the next.
+func (a *Announcer) announce(ctx context.Context, event Event) (time.Duration, error) {
+	resp, err := a.Tracker.Announce(ctx, a.request(event))
+	var warning *WarningError
+	if err != nil {
+		a.report(err)
+		if !errors.As(err, &warning) || resp == nil {
+			return a.retryInterval(), err
+		}
+	}
+
+	if resp.TrackerID != "" {
+		a.trackerID = resp.TrackerID
+	}
+	if a.OnPeers != nil && len(resp.Peers) > 0 {
+		a.OnPeers(resp.Peers)
+	}
+	interval := resp.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return max(interval, resp.MinInterval), nil
+}
+
+// retryInterval returns RetryInterval, or defaultRetryInterval when it is not positive.
+func (a *Announcer) retryInterval() time.Duration {
+	if a.RetryInterval <= 0 {
+		return defaultRetryInterval
+	}
+	return a.RetryInterval
+}
+
+// request describes the current state of the download.
+func (a *Announcer) request(event Event) AnnounceRequest {
+	return AnnounceRequest{
+		InfoHash:   a.InfoHash,
+		PeerID:     a.PeerID,
+		Port:       a.Port,
+		Uploaded:   a.Stats.Uploaded(),
+		Downloaded: a.Stats.Downloaded(),
+		Left:       a.Stats.Left(),
+		Event:      event,
+		NumWant:    a.NumWant,
+		Key:        a.key,
+		TrackerID:  a.trackerID,
+	}
+}
+
+func (a *Announcer) report(err error) {
+	if a.OnError != nil {
+		a.OnError(err)
+	}
+}
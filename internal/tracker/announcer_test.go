@@ -0,0 +1,237 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/peer"
+	"github.com/mattheworford/gotorrent/internal/torrentdata"
+)
+
+// fakeTracker records announces and answers them with respond.
+type fakeTracker struct {
+	requests chan AnnounceRequest
+	respond  func(calls int) (*AnnounceResponse, error)
+
+	mu    sync.Mutex
+	calls int
+}
+
+func newFakeTracker(respond func(calls int) (*AnnounceResponse, error)) *fakeTracker {
+	return &fakeTracker{requests: make(chan AnnounceRequest, 100), respond: respond}
+}
+
+func (f *fakeTracker) Announce(ctx context.Context, req AnnounceRequest) (*AnnounceResponse, error) {
+	f.mu.Lock()
+	f.calls++
+	calls := f.calls
+	f.mu.Unlock()
+	f.requests <- req
+	return f.respond(calls)
+}
+
+func (f *fakeTracker) next(t *testing.T) AnnounceRequest {
+	t.Helper()
+	select {
+	case req := <-f.requests:
+		return req
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for an announce")
+		return AnnounceRequest{}
+	}
+}
+
+// nextEvent skips regular announces until one carries an event.
+func (f *fakeTracker) nextEvent(t *testing.T) AnnounceRequest {
+	t.Helper()
+	for {
+		if req := f.next(t); req.Event != EventNone {
+			return req
+		}
+	}
+}
+
+func startAnnouncer(t *testing.T, tracker Tracker, stats *Stats, configure func(*Announcer)) (context.CancelFunc, <-chan error) {
+	torrent := &torrentdata.TorrentData{InfoHash: [20]byte{1, 2, 3}}
+	a := NewAnnouncer(tracker, torrent, [20]byte{4, 5, 6}, 6881, stats)
+	a.RetryInterval = 10 * time.Millisecond
+	if configure != nil {
+		configure(a)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Run(ctx) }()
+	t.Cleanup(cancel)
+	return cancel, done
+}
+
+func TestAnnouncerLifecycle(t *testing.T) {
+	tracker := newFakeTracker(func(int) (*AnnounceResponse, error) {
+		return &AnnounceResponse{
+			Interval:  20 * time.Millisecond,
+			TrackerID: "tid",
+			Peers:     []peer.ConnectionInfo{{IP: net.IPv4(10, 0, 0, 1), Port: 6881}},
+		}, nil
+	})
+	stats := NewStats(100)
+	var peersMu sync.Mutex
+	var peers []peer.ConnectionInfo
+	cancel, done := startAnnouncer(t, tracker, stats, func(a *Announcer) {
+		a.OnPeers = func(p []peer.ConnectionInfo) {
+			peersMu.Lock()
+			defer peersMu.Unlock()
+			peers = append(peers, p...)
+		}
+	})
+
+	started := tracker.next(t)
+	if started.Event != EventStarted {
+		t.Errorf("Unexpected first event: got %v, want %v", started.Event, EventStarted)
+	}
+	if started.Left != 100 || started.TrackerID != "" || started.Key == 0 || started.NumWant != defaultNumWant {
+		t.Errorf("Unexpected started announce: %+v", started)
+	}
+	if started.InfoHash != [20]byte{1, 2, 3} || started.PeerID != [20]byte{4, 5, 6} || started.Port != 6881 {
+		t.Errorf("Unexpected torrent identity: %+v", started)
+	}
+
+	update := tracker.next(t)
+	if update.Event != EventNone {
+		t.Errorf("Unexpected update event: got %v, want none", update.Event)
+	}
+	if update.TrackerID != "tid" || update.Key != started.Key {
+		t.Errorf("Unexpected update announce: %+v", update)
+	}
+
+	stats.AddUploaded(30)
+	stats.AddDownloaded(120)
+	stats.PieceVerified(60)
+	stats.PieceVerified(40)
+	completed := tracker.nextEvent(t)
+	if completed.Event != EventCompleted {
+		t.Errorf("Unexpected event: got %v, want %v", completed.Event, EventCompleted)
+	}
+	if completed.Uploaded != 30 || completed.Downloaded != 120 || completed.Left != 0 {
+		t.Errorf("Unexpected completed announce: %+v", completed)
+	}
+
+	cancel()
+	stopped := tracker.nextEvent(t)
+	if stopped.Event != EventStopped {
+		t.Errorf("Unexpected event: got %v, want %v", stopped.Event, EventStopped)
+	}
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Unexpected error: got %v, want %v", err, context.Canceled)
+	}
+
+	peersMu.Lock()
+	defer peersMu.Unlock()
+	if len(peers) == 0 {
+		t.Error("Expected peers to be reported")
+	}
+}
+
+func TestAnnouncerRetriesFailedStart(t *testing.T) {
+	tracker := newFakeTracker(func(calls int) (*AnnounceResponse, error) {
+		if calls == 1 {
+			return nil, &FailureError{Reason: "overloaded"}
+		}
+		return &AnnounceResponse{Interval: time.Hour}, nil
+	})
+	errs := make(chan error, 10)
+	cancel, done := startAnnouncer(t, tracker, NewStats(100), func(a *Announcer) {
+		a.OnError = func(err error) { errs <- err }
+	})
+
+	for i := 0; i < 2; i++ {
+		if req := tracker.next(t); req.Event != EventStarted {
+			t.Errorf("Unexpected event of announce %d: got %v, want %v", i+1, req.Event, EventStarted)
+		}
+	}
+	var failure *FailureError
+	if err := <-errs; !errors.As(err, &failure) {
+		t.Errorf("Unexpected error: got %v, want a *FailureError", err)
+	}
+
+	cancel()
+	<-done
+	if req := tracker.next(t); req.Event != EventStopped {
+		t.Errorf("Unexpected event: got %v, want %v", req.Event, EventStopped)
+	}
+}
+
+func TestAnnouncerRetryInterval(t *testing.T) {
+	tracker := newFakeTracker(func(int) (*AnnounceResponse, error) {
+		return nil, errors.New("unreachable")
+	})
+	testCases := []struct {
+		name     string
+		retry    time.Duration
+		expected time.Duration
+	}{
+		{"Set", time.Second, time.Second},
+		{"Zero", 0, defaultRetryInterval},
+		{"Negative", -time.Second, defaultRetryInterval},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := &Announcer{Tracker: tracker, Stats: NewStats(100), RetryInterval: tc.retry}
+			wait, err := a.announce(context.Background(), EventStarted)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if wait != tc.expected {
+				t.Errorf("Unexpected wait: got %v, want %v", wait, tc.expected)
+			}
+		})
+	}
+}
+
+func TestAnnouncerSeeding(t *testing.T) {
+	tracker := newFakeTracker(func(calls int) (*AnnounceResponse, error) {
+		if calls == 2 {
+			return &AnnounceResponse{Interval: time.Millisecond}, &WarningError{Message: "slow down"}
+		}
+		return &AnnounceResponse{Interval: time.Millisecond, MinInterval: 10 * time.Millisecond}, nil
+	})
+	cancel, done := startAnnouncer(t, tracker, NewStats(0), nil)
+
+	if req := tracker.next(t); req.Event != EventStarted || req.Left != 0 {
+		t.Errorf("Unexpected started announce: %+v", req)
+	}
+	// A warning does not repeat the started event, and a seed never reports completion.
+	for i := 0; i < 3; i++ {
+		if req := tracker.next(t); req.Event != EventNone {
+			t.Errorf("Unexpected event: got %v, want none", req.Event)
+		}
+	}
+
+	cancel()
+	<-done
+	if req := tracker.nextEvent(t); req.Event != EventStopped {
+		t.Errorf("Unexpected event: got %v, want %v", req.Event, EventStopped)
+	}
+}
+
+func TestAnnouncerNoStopWithoutStart(t *testing.T) {
+	tracker := newFakeTracker(func(int) (*AnnounceResponse, error) {
+		return nil, errors.New("unreachable")
+	})
+	cancel, done := startAnnouncer(t, tracker, NewStats(100), func(a *Announcer) {
+		a.RetryInterval = time.Hour
+	})
+
+	tracker.next(t)
+	cancel()
+	<-done
+	select {
+	case req := <-tracker.requests:
+		t.Errorf("Unexpected announce after shutdown: %+v", req)
+	default:
+	}
+}
@@ -0,0 +1,158 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/peer"
+)
+
+// DualStack announces to a tracker over IPv4 and IPv6 at once, so that peers of both
+// address families learn about the client, and merges the swarms it returns. A DualStack
+// from NewDualStack advertises the client's IPv6 address (BEP 7) when an announce leaves
+// IPv6 unset; one built as a literal sends requests unchanged.
+type DualStack struct {
+	IPv4 Tracker
+	IPv6 Tracker
+
+	// address is the host:port of the tracker, used to find the local IPv6 address.
+	address string
+}
+
+// NewDualStack creates a DualStack for an http, https or udp announce URL.
+func NewDualStack(announceURL string) (*DualStack, error) {
+	u, err := url.Parse(announceURL)
+	if err != nil {
+		return nil, fmt.Errorf("tracker: failed to parse announce URL: %v", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		ipv4, ipv6 := NewHTTPTracker(announceURL), NewHTTPTracker(announceURL)
+		ipv4.HTTPClient = httpClientFor("tcp4")
+		ipv6.HTTPClient = httpClientFor("tcp6")
+		port := u.Port()
+		if port == "" {
+			port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+		}
+		return &DualStack{IPv4: ipv4, IPv6: ipv6, address: net.JoinHostPort(u.Hostname(), port)}, nil
+	case "udp":
+		ipv4, err := NewUDPTracker(announceURL)
+		if err != nil {
+			return nil, err
+		}
+		ipv6, _ := NewUDPTracker(announceURL)
+		ipv4.Network, ipv6.Network = "udp4", "udp6"
+		return &DualStack{IPv4: ipv4, IPv6: ipv6, address: ipv4.Address}, nil
+	}
+	return nil, fmt.Errorf("tracker: unsupported URL scheme %q", u.Scheme)
+}
+
+// httpClientFor returns an HTTP client whose connections use only the given network.
+func httpClientFor(network string) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport.DialContext = func(ctx context.Context, _, address string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, address)
+	}
+	return &http.Client{Transport: transport}
+}
+
+// localIPv6 returns the local address of the IPv6 route to address, or nil if there is no
+// such route. No packets are sent.
+func localIPv6(ctx context.Context, address string) net.IP {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp6", address)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	ip := conn.LocalAddr().(*net.UDPAddr).IP
+	if ip.To4() != nil || ip.IsLinkLocalUnicast() {
+		return nil
+	}
+	return ip
+}
+
+// Announce announces over both address families concurrently. It succeeds if either does:
+// the peers are combined, the swarm counts are the larger of the two, and the intervals
+// are the longer of the two so that neither tracker is announced to too often. Warnings
+// are returned as from a single tracker.
+func (d *DualStack) Announce(ctx context.Context, req AnnounceRequest) (*AnnounceResponse, error) {
+	if req.IPv6 == nil && d.address != "" {
+		req.IPv6 = localIPv6(ctx, d.address)
+	}
+	var wg sync.WaitGroup
+	var resps [2]*AnnounceResponse
+	var errs [2]error
+	for i, t := range []Tracker{d.IPv4, d.IPv6} {
+		wg.Add(1)
+		go func(i int, t Tracker) {
+			defer wg.Done()
+			resps[i], errs[i] = t.Announce(ctx, req)
+		}(i, t)
+	}
+	wg.Wait()
+
+	var merged *AnnounceResponse
+	var warning error
+	seen := make(map[string]bool)
+	for i, resp := range resps {
+		var w *WarningError
+		if (errs[i] != nil && !errors.As(errs[i], &w)) || resp == nil {
+			continue
+		}
+		if errs[i] != nil && warning == nil {
+			warning = errs[i]
+		}
+		if merged == nil {
+			merged = &AnnounceResponse{TrackerID: resp.TrackerID}
+		}
+		merged.Interval = max(merged.Interval, resp.Interval)
+		merged.MinInterval = max(merged.MinInterval, resp.MinInterval)
+		merged.Complete = max(merged.Complete, resp.Complete)
+		merged.Incomplete = max(merged.Incomplete, resp.Incomplete)
+		merged.Peers = appendNewPeers(merged.Peers, resp.Peers, seen)
+	}
+	if merged == nil {
+		return nil, errors.Join(errs[0], errs[1])
+	}
+	return merged, warning
+}
+
+// appendNewPeers appends the peers whose address is not yet in seen.
+func appendNewPeers(peers, more []peer.ConnectionInfo, seen map[string]bool) []peer.ConnectionInfo {
+	for _, p := range more {
+		if address := p.String(); !seen[address] {
+			seen[address] = true
+			peers = append(peers, p)
+		}
+	}
+	return peers
+}
+
+// Scrape scrapes over IPv4, falling back to IPv6; the statistics are the same either way.
+func (d *DualStack) Scrape(ctx context.Context, infoHashes [][20]byte) (map[[20]byte]ScrapeResult, error) {
+	var errs []error
+	for _, t := range []Tracker{d.IPv4, d.IPv6} {
+		scraper, ok := t.(Scraper)
+		if !ok {
+			errs = append(errs, ErrScrapeUnsupported)
+			continue
+		}
+		results, err := scraper.Scrape(ctx, infoHashes)
+		if err == nil {
+			return results, nil
+		}
+		if errors.Is(err, ErrScrapeUnsupported) {
+			return nil, err
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
@@ -0,0 +1,153 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/peer"
+)
+
+func respondWith(resp *AnnounceResponse, err error) func(int) (*AnnounceResponse, error) {
+	return func(int) (*AnnounceResponse, error) { return resp, err }
+}
+
+func TestDualStackAnnounce(t *testing.T) {
+	v4Peer := peer.ConnectionInfo{IP: net.IPv4(10, 0, 0, 1), Port: 6881}
+	v6Peer := peer.ConnectionInfo{IP: net.ParseIP("2001:db8::1"), Port: 6881}
+	ipv4Resp := &AnnounceResponse{Interval: 30 * time.Minute, Complete: 4, Incomplete: 1, TrackerID: "v4", Peers: []peer.ConnectionInfo{v4Peer}}
+	ipv6Resp := &AnnounceResponse{Interval: time.Hour, MinInterval: time.Minute, Complete: 2, Incomplete: 3, Peers: []peer.ConnectionInfo{v6Peer, v4Peer}}
+	failure := &FailureError{Reason: "unreachable"}
+
+	testCases := []struct {
+		name     string
+		ipv4     func(int) (*AnnounceResponse, error)
+		ipv6     func(int) (*AnnounceResponse, error)
+		expected *AnnounceResponse
+		warning  bool
+	}{
+		{
+			name: "BothSucceed",
+			ipv4: respondWith(ipv4Resp, nil),
+			ipv6: respondWith(ipv6Resp, nil),
+			expected: &AnnounceResponse{
+				Interval: time.Hour, MinInterval: time.Minute, Complete: 4, Incomplete: 3, TrackerID: "v4",
+				Peers: []peer.ConnectionInfo{v4Peer, v6Peer},
+			},
+		},
+		{
+			name:     "IPv6Fails",
+			ipv4:     respondWith(ipv4Resp, nil),
+			ipv6:     respondWith(nil, failure),
+			expected: ipv4Resp,
+		},
+		{
+			name:     "IPv4FailsWithWarningFromIPv6",
+			ipv4:     respondWith(nil, errors.New("no route")),
+			ipv6:     respondWith(ipv4Resp, &WarningError{Message: "slow down"}),
+			expected: ipv4Resp,
+			warning:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &DualStack{IPv4: newFakeTracker(tc.ipv4), IPv6: newFakeTracker(tc.ipv6)}
+			resp, err := d.Announce(context.Background(), AnnounceRequest{})
+			var warning *WarningError
+			if tc.warning != errors.As(err, &warning) || (!tc.warning && err != nil) {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(resp, tc.expected) {
+				t.Errorf("Unexpected response: got %+v, want %+v", resp, tc.expected)
+			}
+		})
+	}
+
+	t.Run("BothFail", func(t *testing.T) {
+		d := &DualStack{IPv4: newFakeTracker(respondWith(nil, failure)), IPv6: newFakeTracker(respondWith(nil, errors.New("no route")))}
+		resp, err := d.Announce(context.Background(), AnnounceRequest{})
+		var got *FailureError
+		if !errors.As(err, &got) || resp != nil {
+			t.Errorf("Unexpected result: got %+v, %v", resp, err)
+		}
+	})
+}
+
+func TestNewDualStack(t *testing.T) {
+	// The stand-in listens on IPv4 only, so the IPv6 announce fails and the IPv4 one carries.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("d8:intervali900e5:peers6:\x0a\x00\x00\x01\x1a\xe1e"))
+	}))
+	defer server.Close()
+
+	d, err := NewDualStack(server.URL + "/announce")
+	if err != nil {
+		t.Fatalf("NewDualStack failed: %v", err)
+	}
+	resp, err := d.Announce(context.Background(), AnnounceRequest{})
+	if err != nil {
+		t.Fatalf("Announce failed: %v", err)
+	}
+	expected := []peer.ConnectionInfo{{IP: net.IPv4(10, 0, 0, 1), Port: 6881}}
+	if !reflect.DeepEqual(resp.Peers, expected) {
+		t.Errorf("Unexpected peers: got %v, want %v", resp.Peers, expected)
+	}
+	if _, err := d.IPv6.Announce(context.Background(), AnnounceRequest{}); err == nil {
+		t.Error("Expected the IPv6 announce to an IPv4 address to fail")
+	}
+
+	udp, err := NewDualStack("udp://tracker.example.com:6969")
+	if err != nil {
+		t.Fatalf("NewDualStack failed: %v", err)
+	}
+	if got := []string{udp.IPv4.(*UDPTracker).Network, udp.IPv6.(*UDPTracker).Network}; !reflect.DeepEqual(got, []string{"udp4", "udp6"}) {
+		t.Errorf("Unexpected networks: got %v", got)
+	}
+}
+
+func TestDualStackAdvertisesIPv6(t *testing.T) {
+	listener, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("Failed to listen on IPv6 loopback: %v", err)
+	}
+	advertised := make(chan string, 2)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		advertised <- r.URL.Query().Get("ipv6")
+		w.Write([]byte("d8:intervali900e5:peers0:e"))
+	}))
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	d, err := NewDualStack(server.URL + "/announce")
+	if err != nil {
+		t.Fatalf("NewDualStack failed: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		ipv6     net.IP
+		expected string
+	}{
+		{"Unset", nil, "::1"},
+		{"SetByCaller", net.ParseIP("2001:db8::1"), "2001:db8::1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := d.Announce(context.Background(), AnnounceRequest{IPv6: tc.ipv6}); err != nil {
+				t.Fatalf("Announce failed: %v", err)
+			}
+			if got := <-advertised; got != tc.expected {
+				t.Errorf("Unexpected ipv6 parameter: got %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/bencode"
+	"github.com/mattheworford/gotorrent/internal/peer"
+)
+
+// maxResponseSize bounds the bencoded body accepted from an HTTP tracker.
+const maxResponseSize = 2 << 20
+
+// HTTPTracker announces to a tracker over HTTP or HTTPS.
+type HTTPTracker struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+// NewHTTPTracker creates an HTTPTracker for the announce URL.
+func NewHTTPTracker(announceURL string) *HTTPTracker {
+	return &HTTPTracker{URL: announceURL, HTTPClient: http.DefaultClient}
+}
+
+// httpResponse is the bencoded body of an announce response. Peers is either a compact
+// string or a list of dictionaries; Peers6 holds compact IPv6 entries (BEP 7).
+type httpResponse struct {
+	FailureReason  string             `bencode:"failure reason"`
+	WarningMessage string             `bencode:"warning message"`
+	Interval       int64              `bencode:"interval"`
+	MinInterval    int64              `bencode:"min interval"`
+	TrackerID      string             `bencode:"tracker id"`
+	Complete       int                `bencode:"complete"`
+	Incomplete     int                `bencode:"incomplete"`
+	Peers          bencode.RawMessage `bencode:"peers"`
+	Peers6         []byte             `bencode:"peers6"`
+}
+
+func (r *httpResponse) failureReason() string { return r.FailureReason }
+
+// httpScrapeResponse is the bencoded body of a scrape response, keyed by raw info hash.
+type httpScrapeResponse struct {
+	FailureReason string                      `bencode:"failure reason"`
+	Files         map[string]httpScrapeResult `bencode:"files"`
+}
+
+type httpScrapeResult struct {
+	Complete   int `bencode:"complete"`
+	Downloaded int `bencode:"downloaded"`
+	Incomplete int `bencode:"incomplete"`
+}
+
+func (r *httpScrapeResponse) failureReason() string { return r.FailureReason }
+
+// dictionaryPeer is an entry of the original, non-compact peer list.
+type dictionaryPeer struct {
+	PeerID string `bencode:"peer id"`
+	IP     string `bencode:"ip"`
+	Port   int    `bencode:"port"`
+}
+
+// Announce reports the download to the tracker and returns the swarm it knows about. A
+// rejected announce yields a *FailureError; a warning yields a *WarningError together
+// with the response.
+func (t *HTTPTracker) Announce(ctx context.Context, req AnnounceRequest) (*AnnounceResponse, error) {
+	announceURL, err := t.announceURL(req)
+	if err != nil {
+		return nil, err
+	}
+	var body httpResponse
+	if err := t.get(ctx, announceURL, &body); err != nil {
+		return nil, err
+	}
+
+	peers, err := decodePeers(body.Peers)
+	if err != nil {
+		return nil, err
+	}
+	if len(body.Peers6) > 0 {
+		peers6, err := peer.DecodeConnectionInfo6(body.Peers6)
+		if err != nil {
+			return nil, fmt.Errorf("tracker: invalid peers6: %w", err)
+		}
+		peers = append(peers, peers6...)
+	}
+	announceResp := &AnnounceResponse{
+		Interval:    time.Duration(body.Interval) * time.Second,
+		MinInterval: time.Duration(body.MinInterval) * time.Second,
+		Complete:    body.Complete,
+		Incomplete:  body.Incomplete,
+		TrackerID:   body.TrackerID,
+		Peers:       peers,
+	}
+	if body.WarningMessage != "" {
+		return announceResp, &WarningError{Message: body.WarningMessage}
+	}
+	return announceResp, nil
+}
+
+// Scrape returns the swarm statistics of the given torrents from a single request. Torrents
+// the tracker does not know about are missing from the result.
+func (t *HTTPTracker) Scrape(ctx context.Context, infoHashes [][20]byte) (map[[20]byte]ScrapeResult, error) {
+	scrapeURL, err := t.ScrapeURL()
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	requested := make(map[[20]byte]bool, len(infoHashes))
+	for _, infoHash := range infoHashes {
+		params.Add("info_hash", string(infoHash[:]))
+		requested[infoHash] = true
+	}
+
+	var body httpScrapeResponse
+	if err := t.get(ctx, appendQuery(scrapeURL, params), &body); err != nil {
+		return nil, err
+	}
+	results := make(map[[20]byte]ScrapeResult, len(body.Files))
+	for key, file := range body.Files {
+		if len(key) != 20 || !requested[[20]byte([]byte(key))] {
+			continue
+		}
+		results[[20]byte([]byte(key))] = ScrapeResult{
+			Complete:   file.Complete,
+			Incomplete: file.Incomplete,
+			Downloaded: file.Downloaded,
+		}
+	}
+	return results, nil
+}
+
+// ScrapeURL derives the scrape URL from the announce URL by the usual convention: the last
+// path component must begin with "announce", which is replaced by "scrape". Other announce
+// URLs yield ErrScrapeUnsupported.
+func (t *HTTPTracker) ScrapeURL() (string, error) {
+	u, err := t.parseURL()
+	if err != nil {
+		return "", err
+	}
+	slash := strings.LastIndex(u.Path, "/")
+	last := u.Path[slash+1:]
+	if !strings.HasPrefix(last, "announce") {
+		return "", ErrScrapeUnsupported
+	}
+	u.Path = u.Path[:slash+1] + "scrape" + strings.TrimPrefix(last, "announce")
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// get fetches a bencoded tracker response into v. A failure reason takes precedence over
+// an error status, since trackers send both.
+func (t *HTTPTracker) get(ctx context.Context, rawURL string, v interface{ failureReason() string }) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("tracker: failed to create request: %w", err)
+	}
+	client := t.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("tracker: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	decodeErr := decodeBody(resp.Body, v)
+	if reason := v.failureReason(); decodeErr == nil && reason != "" {
+		return &FailureError{Reason: reason}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("tracker: unexpected status %s", resp.Status)
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("tracker: failed to decode response: %w", decodeErr)
+	}
+	return nil
+}
+
+// parseURL parses the tracker URL, which must be http or https.
+func (t *HTTPTracker) parseURL() (*url.URL, error) {
+	u, err := url.Parse(t.URL)
+	if err != nil {
+		return nil, fmt.Errorf("tracker: failed to parse announce URL: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("tracker: unsupported URL scheme %q", u.Scheme)
+	}
+	return u, nil
+}
+
+// appendQuery adds params to a URL, keeping any query it already carries.
+func appendQuery(rawURL string, params url.Values) string {
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+	return rawURL + separator + params.Encode()
+}
+
+// announceURL appends the announce parameters to the tracker URL, keeping any query it
+// already carries, such as a private tracker's passkey.
+func (t *HTTPTracker) announceURL(req AnnounceRequest) (string, error) {
+	u, err := t.parseURL()
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("info_hash", string(req.InfoHash[:]))
+	params.Set("peer_id", string(req.PeerID[:]))
+	params.Set("port", strconv.Itoa(int(req.Port)))
+	params.Set("uploaded", strconv.FormatInt(req.Uploaded, 10))
+	params.Set("downloaded", strconv.FormatInt(req.Downloaded, 10))
+	params.Set("left", strconv.FormatInt(req.Left, 10))
+	params.Set("compact", "1")
+	if event := req.Event.String(); event != "" {
+		params.Set("event", event)
+	}
+	if req.NumWant > 0 {
+		params.Set("numwant", strconv.Itoa(req.NumWant))
+	}
+	if req.Key != 0 {
+		params.Set("key", fmt.Sprintf("%08X", req.Key))
+	}
+	if req.TrackerID != "" {
+		params.Set("trackerid", req.TrackerID)
+	}
+	if req.IPv6 != nil {
+		params.Set("ipv6", req.IPv6.String())
+	}
+
+	return appendQuery(u.String(), params), nil
+}
+
+// decodeBody decodes a bencoded tracker response, bounding its size.
+func decodeBody(r io.Reader, v interface{}) error {
+	d := bencode.NewDecoder(io.LimitReader(r, maxResponseSize))
+	d.Limits.MaxSize = maxResponseSize
+	return d.Decode(v)
+}
+
+// decodePeers decodes either peer list model. Dictionary entries whose ip is not a
+// literal address are skipped rather than resolved.
+func decodePeers(raw bencode.RawMessage) ([]peer.ConnectionInfo, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	if raw[0] != 'l' {
+		var compact []byte
+		if err := bencode.Unmarshal(raw, &compact); err != nil {
+			return nil, fmt.Errorf("tracker: invalid peers: %w", err)
+		}
+		if len(compact) == 0 {
+			return nil, nil
+		}
+		peers, err := peer.DecodeConnectionInfo(compact)
+		if err != nil {
+			return nil, fmt.Errorf("tracker: invalid peers: %w", err)
+		}
+		return peers, nil
+	}
+
+	var entries []dictionaryPeer
+	if err := bencode.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("tracker: invalid peers: %w", err)
+	}
+	var peers []peer.ConnectionInfo
+	for _, entry := range entries {
+		ip := net.ParseIP(entry.IP)
+		if ip == nil || entry.Port <= 0 || entry.Port > 0xffff {
+			continue
+		}
+		peers = append(peers, peer.ConnectionInfo{IP: ip, Port: uint16(entry.Port)})
+	}
+	return peers, nil
+}
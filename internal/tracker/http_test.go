@@ -0,0 +1,318 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/peer"
+)
+
+func TestHTTPTrackerAnnounce(t *testing.T) {
+	request := AnnounceRequest{
+		InfoHash:   [20]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20},
+		PeerID:     [20]byte{21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34, 35, 36, 37, 38, 39, 40},
+		Port:       6881,
+		Uploaded:   100,
+		Downloaded: 200,
+		Left:       300,
+	}
+
+	var query map[string][]string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/compact", func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte("d8:completei5e10:incompletei3e8:intervali1800e12:min intervali60e" +
+			"5:peers12:\xc0\xa8\x00\x01\x1a\xe1\x0a\x00\x00\x02\x00\x50" +
+			"6:peers618:\x20\x01\x0d\xb8\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x1a\xe1" +
+			"10:tracker id3:abce"))
+	})
+	mux.HandleFunc("/dictionary", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("d8:intervali900e5:peersl" +
+			"d2:ip11:192.168.0.17:peer id20:aaaaaaaaaaaaaaaaaaaa4:porti6881ee" +
+			"d2:ip16:peer.example.com4:porti6881ee" +
+			"d2:ip8:10.0.0.24:porti80eeee"))
+	})
+	mux.HandleFunc("/failure", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("d14:failure reason12:unregisterede"))
+	})
+	mux.HandleFunc("/failure-status", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("d14:failure reason6:bannede"))
+	})
+	mux.HandleFunc("/warning", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("d8:intervali900e5:peers0:15:warning message11:maintenancee"))
+	})
+	mux.HandleFunc("/malformed-peers6", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("d8:intervali900e5:peers0:6:peers65:abcdee"))
+	})
+	mux.HandleFunc("/malformed", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("d8:intervali900e5:peers5:abcdee"))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Run("Compact", func(t *testing.T) {
+		resp, err := NewHTTPTracker(server.URL+"/compact?passkey=secret").Announce(context.Background(), request)
+		if err != nil {
+			t.Fatalf("Announce failed: %v", err)
+		}
+		expected := &AnnounceResponse{
+			Interval:    30 * time.Minute,
+			MinInterval: time.Minute,
+			Complete:    5,
+			Incomplete:  3,
+			TrackerID:   "abc",
+			Peers: []peer.ConnectionInfo{
+				{IP: net.IPv4(192, 168, 0, 1), Port: 6881},
+				{IP: net.IPv4(10, 0, 0, 2), Port: 80},
+				{IP: net.ParseIP("2001:db8::1"), Port: 6881},
+			},
+		}
+		if !reflect.DeepEqual(resp, expected) {
+			t.Errorf("Unexpected response: got %+v, want %+v", resp, expected)
+		}
+
+		expectedQuery := map[string]string{
+			"passkey":    "secret",
+			"info_hash":  string(request.InfoHash[:]),
+			"peer_id":    string(request.PeerID[:]),
+			"port":       "6881",
+			"uploaded":   "100",
+			"downloaded": "200",
+			"left":       "300",
+			"compact":    "1",
+		}
+		for key, value := range expectedQuery {
+			if got := query[key]; len(got) != 1 || got[0] != value {
+				t.Errorf("Unexpected %s parameter: got %q, want %q", key, got, value)
+			}
+		}
+	})
+
+	t.Run("Dictionary", func(t *testing.T) {
+		resp, err := NewHTTPTracker(server.URL+"/dictionary").Announce(context.Background(), request)
+		if err != nil {
+			t.Fatalf("Announce failed: %v", err)
+		}
+		expected := []peer.ConnectionInfo{
+			{IP: net.ParseIP("192.168.0.1"), Port: 6881},
+			{IP: net.ParseIP("10.0.0.2"), Port: 80},
+		}
+		if !reflect.DeepEqual(resp.Peers, expected) {
+			t.Errorf("Unexpected peers: got %v, want %v", resp.Peers, expected)
+		}
+	})
+
+	t.Run("Warning", func(t *testing.T) {
+		resp, err := NewHTTPTracker(server.URL+"/warning").Announce(context.Background(), request)
+		var warning *WarningError
+		if !errors.As(err, &warning) {
+			t.Fatalf("Unexpected error: got %v, want a *WarningError", err)
+		}
+		if warning.Message != "maintenance" {
+			t.Errorf("Unexpected warning: got %q, want %q", warning.Message, "maintenance")
+		}
+		if resp == nil || resp.Interval != 15*time.Minute {
+			t.Errorf("Unexpected response: got %+v", resp)
+		}
+	})
+
+	for name, path := range map[string]string{"Failure": "/failure", "FailureWithErrorStatus": "/failure-status"} {
+		t.Run(name, func(t *testing.T) {
+			resp, err := NewHTTPTracker(server.URL+path).Announce(context.Background(), request)
+			var failure *FailureError
+			if !errors.As(err, &failure) {
+				t.Fatalf("Unexpected error: got %v, want a *FailureError", err)
+			}
+			if resp != nil {
+				t.Errorf("Unexpected response: got %+v, want nil", resp)
+			}
+		})
+	}
+
+	testCases := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{"NotFound", server.URL + "/missing", "tracker: unexpected status 404 Not Found"},
+		{"MalformedPeers", server.URL + "/malformed", "tracker: invalid peers: malformed connection data: incorrect size"},
+		{"MalformedPeers6", server.URL + "/malformed-peers6", "tracker: invalid peers6: malformed connection data: incorrect size"},
+		{"UnsupportedScheme", "ftp://tracker.example.com/announce", "tracker: unsupported URL scheme \"ftp\""},
+		{"MalformedURL", ":)", "tracker: failed to parse announce URL: parse \":)\": missing protocol scheme"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewHTTPTracker(tc.url).Announce(context.Background(), request)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if err.Error() != tc.expected {
+				t.Errorf("Unexpected error message: got %q, want %q", err.Error(), tc.expected)
+			}
+		})
+	}
+
+	t.Run("Timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := NewHTTPTracker(server.URL+"/slow").Announce(ctx, request)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Unexpected error: got %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+func TestHTTPTrackerAnnounceURL(t *testing.T) {
+	request := AnnounceRequest{
+		InfoHash: [20]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20},
+		PeerID:   [20]byte{21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34, 35, 36, 37, 38, 39, 40},
+		Port:     8080,
+		Left:     1024,
+	}
+	base := "http://tracker.example.com?compact=1&downloaded=0&info_hash=%01%02%03%04%05%06%07%08%09%0A%0B%0C%0D%0E%0F%10%11%12%13%14"
+
+	testCases := []struct {
+		name     string
+		modify   func(*AnnounceRequest)
+		expected string
+	}{
+		{
+			name:     "Minimal",
+			modify:   func(*AnnounceRequest) {},
+			expected: base + "&left=1024&peer_id=%15%16%17%18%19%1A%1B%1C%1D%1E%1F+%21%22%23%24%25%26%27%28&port=8080&uploaded=0",
+		},
+		{
+			name: "AllParameters",
+			modify: func(r *AnnounceRequest) {
+				r.Uploaded, r.Downloaded, r.Left = 512, 256, 768
+				r.Event = EventStarted
+				r.NumWant = 50
+				r.Key = 0xbeef
+				r.TrackerID = "tid"
+				r.IPv6 = net.ParseIP("2001:db8::1")
+			},
+			expected: "http://tracker.example.com?compact=1&downloaded=256&event=started" +
+				"&info_hash=%01%02%03%04%05%06%07%08%09%0A%0B%0C%0D%0E%0F%10%11%12%13%14" +
+				"&ipv6=2001%3Adb8%3A%3A1&key=0000BEEF&left=768&numwant=50&peer_id=%15%16%17%18%19%1A%1B%1C%1D%1E%1F+%21%22%23%24%25%26%27%28" +
+				"&port=8080&trackerid=tid&uploaded=512",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := request
+			tc.modify(&req)
+			got, err := NewHTTPTracker("http://tracker.example.com").announceURL(req)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("Unexpected URL: got %s, want %s", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestHTTPTrackerScrapeURL(t *testing.T) {
+	testCases := []struct {
+		name       string
+		announce   string
+		expected   string
+		errMessage string
+	}{
+		{"Plain", "http://example.com/announce", "http://example.com/scrape", ""},
+		{"Suffix", "http://example.com/x/announce.php", "http://example.com/x/scrape.php", ""},
+		{"Passkey", "https://example.com/announce?passkey=abc", "https://example.com/scrape?passkey=abc", ""},
+		{"PasskeyInPath", "http://example.com/abc123/announce", "http://example.com/abc123/scrape", ""},
+		{"ComponentAnnounceWithSuffix", "http://example.com/announce_v2", "http://example.com/scrape_v2", ""},
+		{"NotLastComponent", "http://example.com/announce/x", "", "tracker: scrape not supported"},
+		{"NoAnnounce", "http://example.com/a", "", "tracker: scrape not supported"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewHTTPTracker(tc.announce).ScrapeURL()
+			if tc.errMessage != "" {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				} else if err.Error() != tc.errMessage {
+					t.Errorf("Unexpected error message: got %q, want %q", err.Error(), tc.errMessage)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("Unexpected scrape URL: got %s, want %s", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestHTTPTrackerScrape(t *testing.T) {
+	first := [20]byte([]byte(strings.Repeat("a", 20)))
+	second := [20]byte([]byte(strings.Repeat("b", 20)))
+	var query map[string][]string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/scrape", func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte("d5:filesd" +
+			"20:" + string(first[:]) + "d8:completei5e10:downloadedi50e10:incompletei10ee" +
+			"20:" + string(second[:]) + "d8:completei0e10:downloadedi1e10:incompletei2ee" +
+			"20:cccccccccccccccccccc" + "d8:completei9e10:downloadedi9e10:incompletei9ee" +
+			"ee"))
+	})
+	mux.HandleFunc("/failing/scrape", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("d14:failure reason15:scrape disablede"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Run("Batch", func(t *testing.T) {
+		results, err := NewHTTPTracker(server.URL+"/announce?passkey=abc").Scrape(context.Background(), [][20]byte{first, second})
+		if err != nil {
+			t.Fatalf("Scrape failed: %v", err)
+		}
+		expected := map[[20]byte]ScrapeResult{
+			first:  {Complete: 5, Incomplete: 10, Downloaded: 50},
+			second: {Complete: 0, Incomplete: 2, Downloaded: 1},
+		}
+		if !reflect.DeepEqual(results, expected) {
+			t.Errorf("Unexpected results: got %+v, want %+v", results, expected)
+		}
+		if got := query["info_hash"]; !reflect.DeepEqual(got, []string{string(first[:]), string(second[:])}) {
+			t.Errorf("Unexpected info_hash parameters: got %q", got)
+		}
+		if got := query["passkey"]; !reflect.DeepEqual(got, []string{"abc"}) {
+			t.Errorf("Unexpected passkey parameter: got %q", got)
+		}
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		_, err := NewHTTPTracker(server.URL+"/failing/announce").Scrape(context.Background(), [][20]byte{first})
+		var failure *FailureError
+		if !errors.As(err, &failure) || failure.Reason != "scrape disabled" {
+			t.Errorf("Unexpected error: got %v, want a *FailureError", err)
+		}
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		_, err := NewHTTPTracker(server.URL+"/tracker").Scrape(context.Background(), [][20]byte{first})
+		if !errors.Is(err, ErrScrapeUnsupported) {
+			t.Errorf("Unexpected error: got %v, want %v", err, ErrScrapeUnsupported)
+		}
+	})
+}
@@ -0,0 +1,429 @@
+package tracker
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	socksVersion = 5
+
+	socksNoAuth       = 0
+	socksUserPassword = 2
+
+	socksConnect      = 1
+	socksUDPAssociate = 3
+
+	socksIPv4   = 1
+	socksDomain = 3
+	socksIPv6   = 4
+)
+
+var socksReplies = map[byte]string{
+	1: "general failure",
+	2: "connection not allowed by ruleset",
+	3: "network unreachable",
+	4: "host unreachable",
+	5: "connection refused",
+	6: "TTL expired",
+	7: "command not supported",
+	8: "address type not supported",
+}
+
+// Proxy routes tracker traffic through an HTTP CONNECT or SOCKS5 (RFC 1928) proxy. HTTP
+// trackers are reached through a tunnel, and UDP trackers through a SOCKS5 UDP
+// association. Host names are resolved by the proxy.
+type Proxy struct {
+	// URL is http://host:port or socks5://host:port. Its user information, if any, is
+	// sent as basic or username/password credentials.
+	URL *url.URL
+}
+
+// ParseProxy parses a proxy URL.
+func ParseProxy(rawURL string) (*Proxy, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// The URL itself is left out of the error, as it may hold a password.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("tracker: failed to parse proxy URL: %v", err)
+	}
+	switch u.Scheme {
+	case "http", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("tracker: unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Port() == "" {
+		return nil, fmt.Errorf("tracker: missing port in proxy URL %q", u.Redacted())
+	}
+	return &Proxy{URL: u}, nil
+}
+
+func (p *Proxy) isSOCKS() bool { return p.URL.Scheme != "http" }
+
+// HTTPClient returns an HTTP client whose connections are tunnelled through the proxy.
+func (p *Proxy) HTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = p.DialContext
+	return &http.Client{Transport: transport}
+}
+
+// DialContext opens a TCP connection to address, a host and port, through the proxy.
+func (p *Proxy) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("tracker: cannot dial %s through a proxy", network)
+	}
+	conn, err := p.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tunnel := conn
+	err = withDeadline(ctx, conn, func() error {
+		if p.isSOCKS() {
+			_, err := p.socksRequest(conn, socksConnect, address)
+			return err
+		}
+		tunnel, err = p.httpConnect(conn, address)
+		return err
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tunnel, nil
+}
+
+// DialUDP associates with a SOCKS5 proxy and returns a connection whose packets the proxy
+// relays to and from address. The association ends when the connection is closed.
+func (p *Proxy) DialUDP(ctx context.Context, address string) (net.Conn, error) {
+	if !p.isSOCKS() {
+		return nil, errors.New("tracker: udp trackers can only be reached through a socks5 proxy")
+	}
+	target, err := encodeSocksAddr(address)
+	if err != nil {
+		return nil, err
+	}
+	control, err := p.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var relay *net.UDPAddr
+	err = withDeadline(ctx, control, func() error {
+		// The address we will send from is unknown before dialing the relay, so it is left
+		// unspecified.
+		bound, err := p.socksRequest(control, socksUDPAssociate, "0.0.0.0:0")
+		if err != nil {
+			return err
+		}
+		relay, err = net.ResolveUDPAddr("udp", bound)
+		return err
+	})
+	if err != nil {
+		control.Close()
+		return nil, err
+	}
+	if relay.IP.IsUnspecified() {
+		relay.IP = control.RemoteAddr().(*net.TCPAddr).IP
+	}
+	conn, err := net.DialUDP("udp", nil, relay)
+	if err != nil {
+		control.Close()
+		return nil, fmt.Errorf("tracker: failed to dial proxy relay %s: %w", relay, err)
+	}
+
+	c := &socksUDPConn{UDPConn: conn, control: control, header: append([]byte{0, 0, 0}, target...)}
+	if host, port, err := net.SplitHostPort(address); err == nil {
+		if ip := net.ParseIP(host); ip != nil {
+			portNum, _ := strconv.Atoi(port)
+			c.remote = &net.UDPAddr{IP: ip, Port: portNum}
+		}
+	}
+	return c, nil
+}
+
+func (p *Proxy) dial(ctx context.Context) (net.Conn, error) {
+	conn, err := (&net.Dialer{Timeout: 30 * time.Second}).DialContext(ctx, "tcp", p.URL.Host)
+	if err != nil {
+		return nil, fmt.Errorf("tracker: failed to dial proxy: %w", err)
+	}
+	return conn, nil
+}
+
+// withDeadline runs a handshake on conn, interrupting it when ctx is done.
+func withDeadline(ctx context.Context, conn net.Conn, handshake func() error) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	err := handshake()
+	if !stop() {
+		return ctx.Err()
+	}
+	return err
+}
+
+func (p *Proxy) httpConnect(conn net.Conn, address string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if user := p.URL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, fmt.Errorf("tracker: failed to send proxy request: %w", err)
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		return nil, fmt.Errorf("tracker: failed to read proxy response: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tracker: proxy refused to connect to %s: %s", address, resp.Status)
+	}
+	if r.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: r}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a connection some of whose input has already been buffered.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) { return c.r.Read(b) }
+
+// socksRequest authenticates with a SOCKS5 proxy and sends it a command, returning the
+// address the proxy bound for it.
+func (p *Proxy) socksRequest(conn net.Conn, command byte, address string) (string, error) {
+	methods := []byte{socksNoAuth}
+	if p.URL.User != nil {
+		methods = append(methods, socksUserPassword)
+	}
+	if _, err := conn.Write(append([]byte{socksVersion, byte(len(methods))}, methods...)); err != nil {
+		return "", fmt.Errorf("tracker: failed to send proxy greeting: %w", err)
+	}
+	var choice [2]byte
+	if _, err := io.ReadFull(conn, choice[:]); err != nil {
+		return "", fmt.Errorf("tracker: failed to read proxy greeting: %w", err)
+	}
+	if choice[0] != socksVersion {
+		return "", fmt.Errorf("tracker: unexpected socks version %d", choice[0])
+	}
+	switch choice[1] {
+	case socksNoAuth:
+	case socksUserPassword:
+		if err := p.socksAuthenticate(conn); err != nil {
+			return "", err
+		}
+	default:
+		return "", errors.New("tracker: proxy accepts none of the offered authentication methods")
+	}
+
+	target, err := encodeSocksAddr(address)
+	if err != nil {
+		return "", err
+	}
+	if _, err := conn.Write(append([]byte{socksVersion, command, 0}, target...)); err != nil {
+		return "", fmt.Errorf("tracker: failed to send proxy request: %w", err)
+	}
+	var reply [3]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return "", fmt.Errorf("tracker: failed to read proxy reply: %w", err)
+	}
+	if reply[1] != 0 {
+		reason, ok := socksReplies[reply[1]]
+		if !ok {
+			reason = fmt.Sprintf("error %d", reply[1])
+		}
+		return "", fmt.Errorf("tracker: proxy refused request for %s: %s", address, reason)
+	}
+	host, port, err := readSocksAddr(conn)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(port))), nil
+}
+
+// socksAuthenticate sends the proxy's user name and password (RFC 1929).
+func (p *Proxy) socksAuthenticate(conn net.Conn) error {
+	if p.URL.User == nil {
+		return errors.New("tracker: proxy requires credentials")
+	}
+	username := p.URL.User.Username()
+	password, _ := p.URL.User.Password()
+	if len(username) > 255 || len(password) > 255 {
+		return errors.New("tracker: proxy credentials too long")
+	}
+	msg := append([]byte{1, byte(len(username))}, username...)
+	msg = append(append(msg, byte(len(password))), password...)
+	if _, err := conn.Write(msg); err != nil {
+		return fmt.Errorf("tracker: failed to send proxy credentials: %w", err)
+	}
+	var status [2]byte
+	if _, err := io.ReadFull(conn, status[:]); err != nil {
+		return fmt.Errorf("tracker: failed to read proxy authentication status: %w", err)
+	}
+	if status[1] != 0 {
+		return errors.New("tracker: proxy rejected credentials")
+	}
+	return nil
+}
+
+// encodeSocksAddr encodes a host and port as a SOCKS5 address type, address and port.
+func encodeSocksAddr(address string) ([]byte, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("tracker: invalid address %q: %v", address, err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("tracker: invalid port in %q", address)
+	}
+	var b []byte
+	if ip := net.ParseIP(host); ip.To4() != nil {
+		b = append([]byte{socksIPv4}, ip.To4()...)
+	} else if ip != nil {
+		b = append([]byte{socksIPv6}, ip...)
+	} else if len(host) <= 255 {
+		b = append([]byte{socksDomain, byte(len(host))}, host...)
+	} else {
+		return nil, fmt.Errorf("tracker: host name too long: %q", host)
+	}
+	return binary.BigEndian.AppendUint16(b, uint16(port)), nil
+}
+
+// readSocksAddr reads a SOCKS5 address type, address and port.
+func readSocksAddr(r io.Reader) (string, uint16, error) {
+	var atyp [2]byte
+	if _, err := io.ReadFull(r, atyp[:1]); err != nil {
+		return "", 0, fmt.Errorf("tracker: failed to read proxy address: %w", err)
+	}
+	b := atyp[:1]
+	var size int
+	switch atyp[0] {
+	case socksIPv4:
+		size = net.IPv4len + 2
+	case socksIPv6:
+		size = net.IPv6len + 2
+	case socksDomain:
+		if _, err := io.ReadFull(r, atyp[1:]); err != nil {
+			return "", 0, fmt.Errorf("tracker: failed to read proxy address: %w", err)
+		}
+		b = atyp[:]
+		size = int(atyp[1]) + 2
+	default:
+		return "", 0, fmt.Errorf("tracker: unknown socks address type %d", atyp[0])
+	}
+	rest := make([]byte, size)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return "", 0, fmt.Errorf("tracker: failed to read proxy address: %w", err)
+	}
+	host, port, _, err := parseSocksAddr(append(b, rest...))
+	return host, port, err
+}
+
+// parseSocksAddr parses a SOCKS5 address type, address and port at the start of b,
+// returning the bytes that follow it.
+func parseSocksAddr(b []byte) (string, uint16, []byte, error) {
+	if len(b) < 1 {
+		return "", 0, nil, errors.New("tracker: truncated socks address")
+	}
+	var host string
+	switch b[0] {
+	case socksIPv4, socksIPv6:
+		size := net.IPv4len
+		if b[0] == socksIPv6 {
+			size = net.IPv6len
+		}
+		if len(b) < 1+size+2 {
+			return "", 0, nil, errors.New("tracker: truncated socks address")
+		}
+		host, b = net.IP(b[1:1+size]).String(), b[1+size:]
+	case socksDomain:
+		if len(b) < 2 || len(b) < 2+int(b[1])+2 {
+			return "", 0, nil, errors.New("tracker: truncated socks address")
+		}
+		host, b = string(b[2:2+int(b[1])]), b[2+int(b[1]):]
+	default:
+		return "", 0, nil, fmt.Errorf("tracker: unknown socks address type %d", b[0])
+	}
+	return host, binary.BigEndian.Uint16(b), b[2:], nil
+}
+
+// socksUDPConn exchanges packets with one address through a SOCKS5 relay, adding and
+// removing the relay's headers.
+type socksUDPConn struct {
+	*net.UDPConn
+	control net.Conn
+	header  []byte // reserved bytes, fragment number and target address
+	remote  *net.UDPAddr
+}
+
+func (c *socksUDPConn) Write(b []byte) (int, error) {
+	packet := append(append([]byte(nil), c.header...), b...)
+	if _, err := c.UDPConn.Write(packet); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Read returns the next packet relayed to us. Fragmented packets are dropped, which the
+// protocol allows.
+func (c *socksUDPConn) Read(b []byte) (int, error) {
+	buf := make([]byte, len(b)+262)
+	for {
+		size, err := c.UDPConn.Read(buf)
+		if err != nil {
+			return 0, err
+		}
+		if size < 4 || buf[2] != 0 {
+			continue
+		}
+		host, port, data, err := parseSocksAddr(buf[3:size])
+		if err != nil {
+			continue
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			c.remote = &net.UDPAddr{IP: ip, Port: int(port)}
+		}
+		return copy(b, data), nil
+	}
+}
+
+// RemoteAddr returns the address packets were last relayed from, or nil before any were
+// when the target was given by name.
+func (c *socksUDPConn) RemoteAddr() net.Addr {
+	if c.remote == nil {
+		return nil
+	}
+	return c.remote
+}
+
+func (c *socksUDPConn) Close() error {
+	c.control.Close()
+	return c.UDPConn.Close()
+}
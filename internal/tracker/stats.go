@@ -0,0 +1,79 @@
+package tracker
+
+import "sync"
+
+// Stats accounts the bytes transferred for a torrent, as reported in announces. It is
+// safe for concurrent use.
+type Stats struct {
+	mu         sync.Mutex
+	uploaded   int64
+	downloaded int64
+	left       int64
+
+	complete     chan struct{}
+	completeOnce sync.Once
+}
+
+// NewStats creates Stats for a torrent with the given number of bytes still to verify.
+func NewStats(left int64) *Stats {
+	s := &Stats{left: max(left, 0), complete: make(chan struct{})}
+	if s.left == 0 {
+		s.markComplete()
+	}
+	return s
+}
+
+// AddUploaded records bytes of piece data sent to peers.
+func (s *Stats) AddUploaded(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.uploaded += n
+}
+
+// AddDownloaded records bytes of piece data received from peers, whether or not they verify.
+func (s *Stats) AddDownloaded(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.downloaded += n
+}
+
+// PieceVerified records that a piece of n bytes passed its hash check. Once nothing is
+// left, the channel returned by Complete is closed.
+func (s *Stats) PieceVerified(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.left = max(s.left-n, 0)
+	if s.left == 0 {
+		s.markComplete()
+	}
+}
+
+func (s *Stats) markComplete() {
+	s.completeOnce.Do(func() { close(s.complete) })
+}
+
+// Uploaded returns the number of bytes uploaded.
+func (s *Stats) Uploaded() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.uploaded
+}
+
+// Downloaded returns the number of bytes downloaded.
+func (s *Stats) Downloaded() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.downloaded
+}
+
+// Left returns the number of bytes still to verify.
+func (s *Stats) Left() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.left
+}
+
+// Complete returns a channel that is closed once every piece has verified.
+func (s *Stats) Complete() <-chan struct{} {
+	return s.complete
+}
@@ -0,0 +1,40 @@
+package tracker
+
+import "testing"
+
+func TestStats(t *testing.T) {
+	s := NewStats(100)
+	s.AddUploaded(10)
+	s.AddUploaded(5)
+	s.AddDownloaded(70)
+	s.PieceVerified(64)
+
+	if s.Uploaded() != 15 || s.Downloaded() != 70 || s.Left() != 36 {
+		t.Errorf("Unexpected stats: uploaded %d, downloaded %d, left %d", s.Uploaded(), s.Downloaded(), s.Left())
+	}
+	select {
+	case <-s.Complete():
+		t.Fatal("Expected the download to be incomplete")
+	default:
+	}
+
+	s.PieceVerified(64)
+	if s.Left() != 0 {
+		t.Errorf("Unexpected Left: got %d, want 0", s.Left())
+	}
+	select {
+	case <-s.Complete():
+	default:
+		t.Error("Expected the download to be complete")
+	}
+
+	if seed := NewStats(0); seed.Left() != 0 {
+		t.Errorf("Unexpected Left: got %d, want 0", seed.Left())
+	} else {
+		select {
+		case <-seed.Complete():
+		default:
+			t.Error("Expected a seed to be complete")
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Package tracker announces to BitTorrent trackers and decodes the peers they return.
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/peer"
+)
+
+// Event tells a tracker why an announce is made. The values match those of the UDP
+// tracker protocol (BEP 15).
+type Event uint8
+
+const (
+	EventNone      Event = iota // 0
+	EventCompleted              // 1
+	EventStarted                // 2
+	EventStopped                // 3
+)
+
+func (e Event) String() string {
+	switch e {
+	case EventCompleted:
+		return "completed"
+	case EventStarted:
+		return "started"
+	case EventStopped:
+		return "stopped"
+	}
+	return ""
+}
+
+// AnnounceRequest describes the state of a download reported to a tracker.
+type AnnounceRequest struct {
+	InfoHash   [20]byte
+	PeerID     [20]byte
+	Port       uint16
+	Uploaded   int64
+	Downloaded int64
+	Left       int64
+	Event      Event
+	// NumWant is the number of peers requested; zero leaves it to the tracker.
+	NumWant int
+	// Key identifies the client across IP address changes; zero omits it.
+	Key uint32
+	// TrackerID echoes the tracker id of a previous response.
+	TrackerID string
+	// IPv6, if set, advertises an IPv6 address the client accepts peers on (BEP 7).
+	IPv6 net.IP
+}
+
+// Tracker announces downloads to a tracker.
+type Tracker interface {
+	Announce(ctx context.Context, req AnnounceRequest) (*AnnounceResponse, error)
+}
+
+// Scraper fetches swarm statistics for several torrents at once.
+type Scraper interface {
+	Scrape(ctx context.Context, infoHashes [][20]byte) (map[[20]byte]ScrapeResult, error)
+}
+
+// ErrScrapeUnsupported is returned when a tracker offers no scrape endpoint.
+var ErrScrapeUnsupported = errors.New("tracker: scrape not supported")
+
+// AnnounceResponse holds the swarm information returned by a tracker.
+type AnnounceResponse struct {
+	// Interval is how long the client should wait before announcing again.
+	Interval time.Duration
+	// MinInterval, when set, is the shortest time the client may wait between announces.
+	MinInterval time.Duration
+	// Complete and Incomplete count the seeders and leechers in the swarm.
+	Complete   int
+	Incomplete int
+	// TrackerID must be sent back with later announces when set.
+	TrackerID string
+	Peers     []peer.ConnectionInfo
+}
+
+// ScrapeResult holds the statistics of a torrent's swarm.
+type ScrapeResult struct {
+	// Complete and Incomplete count the seeders and leechers in the swarm.
+	Complete   int
+	Incomplete int
+	// Downloaded counts the completed downloads the tracker has seen.
+	Downloaded int
+}
+
+// New returns the Tracker for an announce URL, chosen by its scheme.
+func New(announceURL string) (Tracker, error) {
+	u, err := url.Parse(announceURL)
+	if err != nil {
+		return nil, fmt.Errorf("tracker: failed to parse announce URL: %v", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return NewHTTPTracker(announceURL), nil
+	case "udp":
+		return NewUDPTracker(announceURL)
+	}
+	return nil, fmt.Errorf("tracker: unsupported URL scheme %q", u.Scheme)
+}
+
+// NewWithProxy is like New but routes the tracker's traffic through proxy unless it is
+// nil. UDP trackers need a SOCKS5 proxy.
+func NewWithProxy(announceURL string, proxy *Proxy) (Tracker, error) {
+	t, err := New(announceURL)
+	if err != nil || proxy == nil {
+		return t, err
+	}
+	switch t := t.(type) {
+	case *HTTPTracker:
+		t.HTTPClient = proxy.HTTPClient()
+	case *UDPTracker:
+		if !proxy.isSOCKS() {
+			return nil, fmt.Errorf("tracker: udp tracker %s needs a socks5 proxy", announceURL)
+		}
+		t.Proxy = proxy
+	}
+	return t, nil
+}
+
+// FailureError is returned when a tracker rejects a request with a failure reason.
+type FailureError struct {
+	Reason string
+}
+
+func (e *FailureError) Error() string {
+	return fmt.Sprintf("tracker: request rejected: %s", e.Reason)
+}
+
+// WarningError is returned alongside a usable response when a tracker accepts an announce
+// but attaches a warning message.
+type WarningError struct {
+	Message string
+}
+
+func (e *WarningError) Error() string {
+	return fmt.Sprintf("tracker: warning: %s", e.Message)
+}
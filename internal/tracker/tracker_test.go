@@ -0,0 +1,42 @@
+package tracker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name       string
+		url        string
+		expected   reflect.Type
+		errMessage string
+	}{
+		{"HTTP", "http://tracker.example.com/announce", reflect.TypeOf(&HTTPTracker{}), ""},
+		{"HTTPS", "https://tracker.example.com/announce", reflect.TypeOf(&HTTPTracker{}), ""},
+		{"UDP", "udp://tracker.example.com:6969/announce", reflect.TypeOf(&UDPTracker{}), ""},
+		{"UDPWithoutPort", "udp://tracker.example.com/announce", nil, "tracker: missing port in announce URL \"udp://tracker.example.com/announce\""},
+		{"UnsupportedScheme", "wss://tracker.example.com", nil, "tracker: unsupported URL scheme \"wss\""},
+		{"Malformed", ":)", nil, "tracker: failed to parse announce URL: parse \":)\": missing protocol scheme"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker, err := New(tc.url)
+			if tc.errMessage != "" {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				} else if err.Error() != tc.errMessage {
+					t.Errorf("Unexpected error message: got %q, want %q", err.Error(), tc.errMessage)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := reflect.TypeOf(tracker); got != tc.expected {
+				t.Errorf("Unexpected tracker type: got %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package tracker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/peer"
+)
+
+const (
+	udpProtocolID = 0x41727101980
+
+	actionConnect  = 0
+	actionAnnounce = 1
+	actionScrape   = 2
+	actionError    = 3
+
+	defaultUDPTimeout    = 15 * time.Second
+	defaultUDPMaxRetries = 8
+	connectionIDLifetime = time.Minute
+
+	// maxScrapeHashes is the most info hashes that fit in one UDP scrape request.
+	maxScrapeHashes = 74
+	maxUDPPacket    = 1 << 16
+)
+
+// errUDPTimeout reports that a single attempt went unanswered and should be retransmitted.
+var errUDPTimeout = errors.New("tracker: udp request timed out")
+
+// UDPTracker announces to and scrapes a tracker over the UDP tracker protocol (BEP 15).
+// Connection IDs are cached for the minute the protocol allows. It is safe for concurrent use.
+type UDPTracker struct {
+	Address string
+	// Network is "udp", or "udp4" or "udp6" to restrict the tracker to one address family.
+	Network string
+	// Timeout is the wait for the first attempt; retransmissions double it each time.
+	// Defaults to 15s (BEP 15).
+	Timeout time.Duration
+	// MaxRetries is the number of retransmissions before giving up. Defaults to 8;
+	// negative disables retransmission.
+	MaxRetries int
+	// Proxy, if set, relays the packets through a SOCKS5 proxy; Network is then ignored.
+	Proxy *Proxy
+
+	now func() time.Time
+
+	mu                  sync.Mutex
+	connectionID        uint64
+	connectionIDExpires time.Time
+}
+
+// NewUDPTracker creates a UDPTracker for an announce URL of the form udp://host:port.
+func NewUDPTracker(announceURL string) (*UDPTracker, error) {
+	u, err := url.Parse(announceURL)
+	if err != nil {
+		return nil, fmt.Errorf("tracker: failed to parse announce URL: %v", err)
+	}
+	if u.Scheme != "udp" {
+		return nil, fmt.Errorf("tracker: unsupported URL scheme %q", u.Scheme)
+	}
+	if u.Port() == "" {
+		return nil, fmt.Errorf("tracker: missing port in announce URL %q", announceURL)
+	}
+	return &UDPTracker{
+		Address:    u.Host,
+		Network:    "udp",
+		Timeout:    defaultUDPTimeout,
+		MaxRetries: defaultUDPMaxRetries,
+		now:        time.Now,
+	}, nil
+}
+
+// Announce reports the download to the tracker and returns the swarm it knows about. An
+// error action from the tracker yields a *FailureError. Trackers reached over IPv6 return
+// IPv6 peers.
+func (t *UDPTracker) Announce(ctx context.Context, req AnnounceRequest) (*AnnounceResponse, error) {
+	numWant := int32(-1)
+	if req.NumWant > 0 {
+		numWant = int32(req.NumWant)
+	}
+	payload := make([]byte, 0, 82)
+	payload = append(payload, req.InfoHash[:]...)
+	payload = append(payload, req.PeerID[:]...)
+	payload = binary.BigEndian.AppendUint64(payload, uint64(req.Downloaded))
+	payload = binary.BigEndian.AppendUint64(payload, uint64(req.Left))
+	payload = binary.BigEndian.AppendUint64(payload, uint64(req.Uploaded))
+	payload = binary.BigEndian.AppendUint32(payload, uint32(req.Event))
+	payload = binary.BigEndian.AppendUint32(payload, 0) // IP address: use the sender's
+	payload = binary.BigEndian.AppendUint32(payload, req.Key)
+	payload = binary.BigEndian.AppendUint32(payload, uint32(numWant))
+	payload = binary.BigEndian.AppendUint16(payload, req.Port)
+
+	resp, remote, err := t.do(ctx, actionAnnounce, payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) < 12 {
+		return nil, errors.New("tracker: malformed udp announce response")
+	}
+	announceResp := &AnnounceResponse{
+		Interval:   time.Duration(binary.BigEndian.Uint32(resp[0:4])) * time.Second,
+		Incomplete: int(binary.BigEndian.Uint32(resp[4:8])),
+		Complete:   int(binary.BigEndian.Uint32(resp[8:12])),
+	}
+	if peers := resp[12:]; len(peers) > 0 {
+		if remote == nil || remote.IP.To4() != nil {
+			announceResp.Peers, err = peer.DecodeConnectionInfo(peers)
+		} else {
+			announceResp.Peers, err = peer.DecodeConnectionInfo6(peers)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("tracker: invalid peers: %w", err)
+		}
+	}
+	return announceResp, nil
+}
+
+// Scrape returns the swarm statistics of the given torrents, splitting them over as many
+// requests as the protocol requires.
+func (t *UDPTracker) Scrape(ctx context.Context, infoHashes [][20]byte) (map[[20]byte]ScrapeResult, error) {
+	results := make(map[[20]byte]ScrapeResult, len(infoHashes))
+	for start := 0; start < len(infoHashes); start += maxScrapeHashes {
+		batch := infoHashes[start:min(start+maxScrapeHashes, len(infoHashes))]
+		payload := make([]byte, 0, 20*len(batch))
+		for _, infoHash := range batch {
+			payload = append(payload, infoHash[:]...)
+		}
+
+		resp, _, err := t.do(ctx, actionScrape, payload)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp) != 12*len(batch) {
+			return nil, errors.New("tracker: malformed udp scrape response")
+		}
+		for i, infoHash := range batch {
+			entry := resp[12*i:]
+			results[infoHash] = ScrapeResult{
+				Complete:   int(binary.BigEndian.Uint32(entry[0:4])),
+				Downloaded: int(binary.BigEndian.Uint32(entry[4:8])),
+				Incomplete: int(binary.BigEndian.Uint32(entry[8:12])),
+			}
+		}
+	}
+	return results, nil
+}
+
+// do performs an action, connecting first when no connection ID is cached, and returns the
+// body of the response after its action and transaction ID together with the tracker's
+// address. Unanswered attempts are retransmitted after 15·2^n seconds, counting connects
+// and requests alike.
+func (t *UDPTracker) do(ctx context.Context, action uint32, payload []byte) ([]byte, *net.UDPAddr, error) {
+	conn, err := t.dial(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	maxRetries := t.maxRetries()
+	for n := 0; n <= maxRetries; n++ {
+		connectionID, ok := t.cachedConnectionID()
+		if !ok {
+			header := binary.BigEndian.AppendUint64(nil, udpProtocolID)
+			resp, err := t.exchange(ctx, conn, n, header, actionConnect, nil)
+			if err == errUDPTimeout {
+				continue
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(resp) < 8 {
+				return nil, nil, errors.New("tracker: malformed udp connect response")
+			}
+			connectionID = binary.BigEndian.Uint64(resp)
+			t.setConnectionID(connectionID)
+		}
+
+		header := binary.BigEndian.AppendUint64(nil, connectionID)
+		resp, err := t.exchange(ctx, conn, n, header, action, payload)
+		if err == errUDPTimeout {
+			continue
+		}
+		var failure *FailureError
+		if errors.As(err, &failure) {
+			// The tracker may have forgotten the connection ID; start afresh next time.
+			t.forgetConnectionID()
+		}
+		// Through a proxy the tracker's address is only known once it has answered.
+		remote, _ := conn.RemoteAddr().(*net.UDPAddr)
+		return resp, remote, err
+	}
+	return nil, nil, fmt.Errorf("tracker: no response from %s after %d attempts", t.Address, maxRetries+1)
+}
+
+func (t *UDPTracker) dial(ctx context.Context) (net.Conn, error) {
+	if t.Proxy != nil {
+		return t.Proxy.DialUDP(ctx, t.Address)
+	}
+	network := t.Network
+	if network == "" {
+		network = "udp"
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, network, t.Address)
+	if err != nil {
+		return nil, fmt.Errorf("tracker: failed to dial %s: %w", t.Address, err)
+	}
+	return conn, nil
+}
+
+// exchange sends one packet of the form header, action, transaction ID, payload and waits
+// for the matching response. Packets with another transaction ID are ignored.
+func (t *UDPTracker) exchange(ctx context.Context, conn net.Conn, n int, header []byte, action uint32, payload []byte) ([]byte, error) {
+	var tid [4]byte
+	if _, err := rand.Read(tid[:]); err != nil {
+		return nil, fmt.Errorf("tracker: failed to generate transaction ID: %w", err)
+	}
+	packet := binary.BigEndian.AppendUint32(header, action)
+	packet = append(packet, tid[:]...)
+	packet = append(packet, payload...)
+	if _, err := conn.Write(packet); err != nil {
+		return nil, fmt.Errorf("tracker: failed to send udp request: %w", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(t.timeout() << n))
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	buf := make([]byte, maxUDPPacket)
+	for {
+		size, err := conn.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return nil, errUDPTimeout
+			}
+			return nil, fmt.Errorf("tracker: failed to read udp response: %w", err)
+		}
+		resp := buf[:size]
+		if size < 8 || [4]byte(resp[4:8]) != tid {
+			continue
+		}
+		switch got := binary.BigEndian.Uint32(resp[0:4]); got {
+		case action:
+			return append([]byte(nil), resp[8:]...), nil
+		case actionError:
+			return nil, &FailureError{Reason: string(resp[8:])}
+		default:
+			return nil, fmt.Errorf("tracker: unexpected udp action %d, expected %d", got, action)
+		}
+	}
+}
+
+func (t *UDPTracker) cachedConnectionID() (uint64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.connectionIDExpires.IsZero() || !t.clock().Before(t.connectionIDExpires) {
+		return 0, false
+	}
+	return t.connectionID, true
+}
+
+func (t *UDPTracker) setConnectionID(id uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.connectionID = id
+	t.connectionIDExpires = t.clock().Add(connectionIDLifetime)
+}
+
+// clock returns the current time, from time.Now unless a test replaced it.
+func (t *UDPTracker) clock() time.Time {
+	if t.now == nil {
+		return time.Now()
+	}
+	return t.now()
+}
+
+// timeout returns Timeout, or defaultUDPTimeout when it is not positive.
+func (t *UDPTracker) timeout() time.Duration {
+	if t.Timeout <= 0 {
+		return defaultUDPTimeout
+	}
+	return t.Timeout
+}
+
+// maxRetries returns MaxRetries, defaulting to defaultUDPMaxRetries when it is zero.
+func (t *UDPTracker) maxRetries() int {
+	switch {
+	case t.MaxRetries == 0:
+		return defaultUDPMaxRetries
+	case t.MaxRetries < 0:
+		return 0
+	}
+	return t.MaxRetries
+}
+
+func (t *UDPTracker) forgetConnectionID() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.connectionIDExpires = time.Time{}
+}
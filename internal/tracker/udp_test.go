@@ -0,0 +1,392 @@
+package tracker
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/peer"
+)
+
+// udpStandIn is a scripted UDP tracker. handle returns the packets sent back in reply to
+// each packet received; returning none drops the packet.
+type udpStandIn struct {
+	conn   net.PacketConn
+	handle func(packet []byte) [][]byte
+
+	mu      sync.Mutex
+	packets [][]byte
+}
+
+func newUDPStandIn(t *testing.T, handle func(packet []byte) [][]byte) *udpStandIn {
+	t.Helper()
+	return newUDPStandInAt(t, "127.0.0.1:0", handle)
+}
+
+func newUDPStandInAt(t *testing.T, address string, handle func(packet []byte) [][]byte) *udpStandIn {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		t.Skipf("Failed to listen on %s: %v", address, err)
+	}
+	s := &udpStandIn{conn: conn, handle: handle}
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			size, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			packet := append([]byte(nil), buf[:size]...)
+			s.mu.Lock()
+			s.packets = append(s.packets, packet)
+			s.mu.Unlock()
+			for _, reply := range s.handle(packet) {
+				conn.WriteTo(reply, addr)
+			}
+		}
+	}()
+	t.Cleanup(func() { conn.Close() })
+	return s
+}
+
+// count returns how many packets with the given action were received.
+func (s *udpStandIn) count(action uint32) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, packet := range s.packets {
+		if binary.BigEndian.Uint32(packet[8:12]) == action {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *udpStandIn) tracker(t *testing.T) *UDPTracker {
+	t.Helper()
+	tracker, err := NewUDPTracker("udp://" + s.conn.LocalAddr().String() + "/announce")
+	if err != nil {
+		t.Fatalf("NewUDPTracker failed: %v", err)
+	}
+	tracker.Timeout = 20 * time.Millisecond
+	tracker.MaxRetries = 3
+	return tracker
+}
+
+// udpReply builds a response packet echoing the transaction ID of the request.
+func udpReply(request []byte, action uint32, body []byte) []byte {
+	reply := binary.BigEndian.AppendUint32(nil, action)
+	reply = append(reply, request[12:16]...)
+	return append(reply, body...)
+}
+
+const testConnectionID = 0x1122334455667788
+
+// serveUDP answers connects with testConnectionID and passes other requests to handle once
+// their connection ID has been checked.
+func serveUDP(handle func(action uint32, request []byte) [][]byte) func([]byte) [][]byte {
+	return func(packet []byte) [][]byte {
+		if len(packet) < 16 {
+			return nil
+		}
+		action := binary.BigEndian.Uint32(packet[8:12])
+		if action == actionConnect {
+			if binary.BigEndian.Uint64(packet) != udpProtocolID {
+				return nil
+			}
+			return [][]byte{udpReply(packet, actionConnect, binary.BigEndian.AppendUint64(nil, testConnectionID))}
+		}
+		if binary.BigEndian.Uint64(packet) != testConnectionID {
+			return [][]byte{udpReply(packet, actionError, []byte("invalid connection id"))}
+		}
+		return handle(action, packet)
+	}
+}
+
+func TestUDPTrackerAnnounce(t *testing.T) {
+	var mu sync.Mutex
+	var announce []byte
+	server := newUDPStandIn(t, serveUDP(func(action uint32, request []byte) [][]byte {
+		mu.Lock()
+		announce = request
+		mu.Unlock()
+		body := []byte{0, 0, 0x07, 0x08, 0, 0, 0, 3, 0, 0, 0, 5, 192, 168, 0, 1, 0x1a, 0xe1, 10, 0, 0, 2, 0, 80}
+		return [][]byte{udpReply(request, actionAnnounce, body)}
+	}))
+	tracker := server.tracker(t)
+	now := time.Now()
+	tracker.now = func() time.Time { return now }
+
+	request := AnnounceRequest{
+		InfoHash:   [20]byte{1, 2, 3},
+		PeerID:     [20]byte{4, 5, 6},
+		Port:       6881,
+		Uploaded:   100,
+		Downloaded: 200,
+		Left:       300,
+		Event:      EventStarted,
+		Key:        0xdeadbeef,
+	}
+	resp, err := tracker.Announce(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Announce failed: %v", err)
+	}
+	expected := &AnnounceResponse{
+		Interval:   1800 * time.Second,
+		Incomplete: 3,
+		Complete:   5,
+		Peers: []peer.ConnectionInfo{
+			{IP: net.IPv4(192, 168, 0, 1), Port: 6881},
+			{IP: net.IPv4(10, 0, 0, 2), Port: 80},
+		},
+	}
+	if !reflect.DeepEqual(resp, expected) {
+		t.Errorf("Unexpected response: got %+v, want %+v", resp, expected)
+	}
+
+	mu.Lock()
+	packet := announce
+	mu.Unlock()
+	if len(packet) != 98 {
+		t.Fatalf("Unexpected announce size: got %d, want 98", len(packet))
+	}
+	fields := []struct {
+		name     string
+		got      interface{}
+		expected interface{}
+	}{
+		{"info_hash", [20]byte(packet[16:36]), request.InfoHash},
+		{"peer_id", [20]byte(packet[36:56]), request.PeerID},
+		{"downloaded", binary.BigEndian.Uint64(packet[56:64]), uint64(200)},
+		{"left", binary.BigEndian.Uint64(packet[64:72]), uint64(300)},
+		{"uploaded", binary.BigEndian.Uint64(packet[72:80]), uint64(100)},
+		{"event", binary.BigEndian.Uint32(packet[80:84]), uint32(2)},
+		{"ip", binary.BigEndian.Uint32(packet[84:88]), uint32(0)},
+		{"key", binary.BigEndian.Uint32(packet[88:92]), uint32(0xdeadbeef)},
+		{"num_want", int32(binary.BigEndian.Uint32(packet[92:96])), int32(-1)},
+		{"port", binary.BigEndian.Uint16(packet[96:98]), uint16(6881)},
+	}
+	for _, f := range fields {
+		if f.got != f.expected {
+			t.Errorf("Unexpected %s: got %v, want %v", f.name, f.got, f.expected)
+		}
+	}
+
+	t.Run("CachesConnectionID", func(t *testing.T) {
+		if _, err := tracker.Announce(context.Background(), request); err != nil {
+			t.Fatalf("Announce failed: %v", err)
+		}
+		if got := server.count(actionConnect); got != 1 {
+			t.Errorf("Unexpected connect count: got %d, want 1", got)
+		}
+	})
+
+	t.Run("ReconnectsAfterExpiry", func(t *testing.T) {
+		now = now.Add(connectionIDLifetime)
+		if _, err := tracker.Announce(context.Background(), request); err != nil {
+			t.Fatalf("Announce failed: %v", err)
+		}
+		if got := server.count(actionConnect); got != 2 {
+			t.Errorf("Unexpected connect count: got %d, want 2", got)
+		}
+	})
+}
+
+func TestUDPTrackerZeroValue(t *testing.T) {
+	server := newUDPStandIn(t, serveUDP(func(action uint32, request []byte) [][]byte {
+		return [][]byte{udpReply(request, actionAnnounce, make([]byte, 12))}
+	}))
+	tracker := &UDPTracker{Address: server.conn.LocalAddr().String()}
+
+	for i := 0; i < 2; i++ {
+		if _, err := tracker.Announce(context.Background(), AnnounceRequest{}); err != nil {
+			t.Fatalf("Announce failed: %v", err)
+		}
+	}
+	if got := server.count(actionConnect); got != 1 {
+		t.Errorf("Unexpected connect count: got %d, want 1", got)
+	}
+
+	t.Run("Defaults", func(t *testing.T) {
+		testCases := []struct {
+			name               string
+			tracker            *UDPTracker
+			expectedTimeout    time.Duration
+			expectedMaxRetries int
+		}{
+			{"Zero", &UDPTracker{}, defaultUDPTimeout, defaultUDPMaxRetries},
+			{"Set", &UDPTracker{Timeout: time.Second, MaxRetries: 2}, time.Second, 2},
+			{"Negative", &UDPTracker{Timeout: -time.Second, MaxRetries: -1}, defaultUDPTimeout, 0},
+		}
+		for _, tc := range testCases {
+			if got := tc.tracker.timeout(); got != tc.expectedTimeout {
+				t.Errorf("%s: unexpected timeout: got %v, want %v", tc.name, got, tc.expectedTimeout)
+			}
+			if got := tc.tracker.maxRetries(); got != tc.expectedMaxRetries {
+				t.Errorf("%s: unexpected retries: got %d, want %d", tc.name, got, tc.expectedMaxRetries)
+			}
+		}
+	})
+}
+
+func TestUDPTrackerRetransmit(t *testing.T) {
+	var mu sync.Mutex
+	received := 0
+	handle := serveUDP(func(action uint32, request []byte) [][]byte {
+		return [][]byte{udpReply(request, actionAnnounce, make([]byte, 12))}
+	})
+	server := newUDPStandIn(t, func(packet []byte) [][]byte {
+		mu.Lock()
+		received++
+		drop := received <= 2
+		mu.Unlock()
+		if drop {
+			return nil
+		}
+		return handle(packet)
+	})
+
+	if _, err := server.tracker(t).Announce(context.Background(), AnnounceRequest{}); err != nil {
+		t.Fatalf("Announce failed: %v", err)
+	}
+	if got := server.count(actionConnect); got != 3 {
+		t.Errorf("Unexpected connect count: got %d, want 3", got)
+	}
+}
+
+func TestUDPTrackerGivesUp(t *testing.T) {
+	server := newUDPStandIn(t, func([]byte) [][]byte { return nil })
+	tracker := server.tracker(t)
+	tracker.Timeout = 5 * time.Millisecond
+	tracker.MaxRetries = 2
+
+	start := time.Now()
+	_, err := tracker.Announce(context.Background(), AnnounceRequest{})
+	expected := "tracker: no response from " + tracker.Address + " after 3 attempts"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("Unexpected error: got %v, want %q", err, expected)
+	}
+	// The attempts wait 5, 10 and 20 milliseconds.
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("Unexpected elapsed time: got %v, want at least 35ms", elapsed)
+	}
+	if got := server.count(actionConnect); got != 3 {
+		t.Errorf("Unexpected connect count: got %d, want 3", got)
+	}
+}
+
+func TestUDPTrackerErrorAction(t *testing.T) {
+	server := newUDPStandIn(t, serveUDP(func(action uint32, request []byte) [][]byte {
+		return [][]byte{udpReply(request, actionError, []byte("unregistered torrent"))}
+	}))
+	tracker := server.tracker(t)
+
+	for i := 1; i <= 2; i++ {
+		_, err := tracker.Announce(context.Background(), AnnounceRequest{})
+		var failure *FailureError
+		if !errors.As(err, &failure) {
+			t.Fatalf("Unexpected error: got %v, want a *FailureError", err)
+		}
+		if failure.Reason != "unregistered torrent" {
+			t.Errorf("Unexpected reason: got %q, want %q", failure.Reason, "unregistered torrent")
+		}
+		// An error forgets the connection ID, so every announce connects again.
+		if got := server.count(actionConnect); got != i {
+			t.Errorf("Unexpected connect count: got %d, want %d", got, i)
+		}
+	}
+}
+
+func TestUDPTrackerIgnoresOtherTransactions(t *testing.T) {
+	server := newUDPStandIn(t, serveUDP(func(action uint32, request []byte) [][]byte {
+		stray := append([]byte(nil), request...)
+		stray[12] ^= 0xff
+		return [][]byte{
+			udpReply(stray, actionError, []byte("not for you")),
+			udpReply(request, actionAnnounce, []byte{0, 0, 0, 60, 0, 0, 0, 0, 0, 0, 0, 0}),
+		}
+	}))
+
+	resp, err := server.tracker(t).Announce(context.Background(), AnnounceRequest{})
+	if err != nil {
+		t.Fatalf("Announce failed: %v", err)
+	}
+	if resp.Interval != time.Minute {
+		t.Errorf("Unexpected Interval: got %v, want %v", resp.Interval, time.Minute)
+	}
+}
+
+func TestUDPTrackerScrape(t *testing.T) {
+	server := newUDPStandIn(t, serveUDP(func(action uint32, request []byte) [][]byte {
+		if action != actionScrape {
+			return nil
+		}
+		var body []byte
+		for hashes := request[16:]; len(hashes) >= 20; hashes = hashes[20:] {
+			// Each torrent reports statistics derived from its first byte.
+			n := uint32(hashes[0])
+			body = binary.BigEndian.AppendUint32(body, n)
+			body = binary.BigEndian.AppendUint32(body, n*2)
+			body = binary.BigEndian.AppendUint32(body, n*3)
+		}
+		return [][]byte{udpReply(request, actionScrape, body)}
+	}))
+
+	infoHashes := make([][20]byte, 80)
+	for i := range infoHashes {
+		infoHashes[i][0] = byte(i + 1)
+	}
+	results, err := server.tracker(t).Scrape(context.Background(), infoHashes)
+	if err != nil {
+		t.Fatalf("Scrape failed: %v", err)
+	}
+	if len(results) != len(infoHashes) {
+		t.Fatalf("Unexpected result count: got %d, want %d", len(results), len(infoHashes))
+	}
+	for i, infoHash := range infoHashes {
+		n := i + 1
+		expected := ScrapeResult{Complete: n, Downloaded: n * 2, Incomplete: n * 3}
+		if got := results[infoHash]; got != expected {
+			t.Errorf("Unexpected result for torrent %d: got %+v, want %+v", n, got, expected)
+		}
+	}
+	if got := server.count(actionScrape); got != 2 {
+		t.Errorf("Unexpected scrape request count: got %d, want 2", got)
+	}
+}
+
+func TestUDPTrackerCancel(t *testing.T) {
+	server := newUDPStandIn(t, func([]byte) [][]byte { return nil })
+	tracker := server.tracker(t)
+	tracker.Timeout = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := tracker.Announce(ctx, AnnounceRequest{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Unexpected error: got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestUDPTrackerIPv6(t *testing.T) {
+	server := newUDPStandInAt(t, "[::1]:0", serveUDP(func(action uint32, request []byte) [][]byte {
+		body := []byte{0, 0, 0, 60, 0, 0, 0, 0, 0, 0, 0, 1}
+		body = append(body, net.ParseIP("2001:db8::1")...)
+		body = append(body, 0x1a, 0xe1)
+		return [][]byte{udpReply(request, actionAnnounce, body)}
+	}))
+
+	resp, err := server.tracker(t).Announce(context.Background(), AnnounceRequest{})
+	if err != nil {
+		t.Fatalf("Announce failed: %v", err)
+	}
+	expected := []peer.ConnectionInfo{{IP: net.ParseIP("2001:db8::1"), Port: 6881}}
+	if !reflect.DeepEqual(resp.Peers, expected) {
+		t.Errorf("Unexpected peers: got %v, want %v", resp.Peers, expected)
+	}
+}
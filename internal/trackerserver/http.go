@@ -0,0 +1,216 @@
+package trackerserver
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/bencode"
+	"github.com/mattheworford/gotorrent/internal/peer"
+	"github.com/mattheworford/gotorrent/internal/tracker"
+)
+
+// HTTPServer serves the announce and scrape endpoints of an HTTP tracker at /announce and
+// /scrape. Peers are listed in compact form, IPv6 peers under peers6 (BEP 7), unless a
+// client asks for compact=0.
+type HTTPServer struct {
+	Store     *Store
+	Allowlist Allowlist
+	// Interval and MinInterval are sent to clients to pace their announces.
+	Interval    time.Duration
+	MinInterval time.Duration
+}
+
+// NewHTTPServer creates an HTTPServer for the swarms of a store.
+func NewHTTPServer(store *Store) *HTTPServer {
+	return &HTTPServer{Store: store, Interval: defaultInterval}
+}
+
+type announceResponse struct {
+	Interval    int64 `bencode:"interval"`
+	MinInterval int64 `bencode:"min interval,omitempty"`
+	Complete    int   `bencode:"complete"`
+	Incomplete  int   `bencode:"incomplete"`
+	// Peers is either a compact string or a list of dictionaryPeer.
+	Peers  interface{} `bencode:"peers"`
+	Peers6 []byte      `bencode:"peers6,omitempty"`
+}
+
+type dictionaryPeer struct {
+	PeerID []byte `bencode:"peer id"`
+	IP     string `bencode:"ip"`
+	Port   uint16 `bencode:"port"`
+}
+
+type scrapeResponse struct {
+	Files map[string]scrapeFile `bencode:"files"`
+}
+
+type scrapeFile struct {
+	Complete   int `bencode:"complete"`
+	Downloaded int `bencode:"downloaded"`
+	Incomplete int `bencode:"incomplete"`
+}
+
+type failureResponse struct {
+	FailureReason string `bencode:"failure reason"`
+}
+
+func (s *HTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	switch r.URL.Path {
+	case "/announce":
+		s.announce(w, r)
+	case "/scrape":
+		s.scrape(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *HTTPServer) announce(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	infoHash, ok := hashParam(query.Get("info_hash"))
+	if !ok {
+		writeFailure(w, "invalid info_hash")
+		return
+	}
+	if !s.Allowlist.Allows(infoHash) {
+		writeFailure(w, "unregistered torrent")
+		return
+	}
+	peerID, ok := hashParam(query.Get("peer_id"))
+	if !ok {
+		writeFailure(w, "invalid peer_id")
+		return
+	}
+	port, err := strconv.ParseUint(query.Get("port"), 10, 16)
+	if err != nil || port == 0 {
+		writeFailure(w, "invalid port")
+		return
+	}
+	left, err := strconv.ParseInt(query.Get("left"), 10, 64)
+	if err != nil || left < 0 {
+		writeFailure(w, "invalid left")
+		return
+	}
+	event, ok := parseEvent(query.Get("event"))
+	if !ok {
+		writeFailure(w, "invalid event")
+		return
+	}
+	numWant, _ := strconv.Atoi(query.Get("numwant"))
+
+	// Clients are reached at the address they connect from, and at the IPv6 address they
+	// advertise when they connect over IPv4.
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		writeFailure(w, "unknown client address")
+		return
+	}
+	addrs := []peer.ConnectionInfo{{IP: net.ParseIP(host), Port: uint16(port)}}
+	if ipv6 := net.ParseIP(query.Get("ipv6")); ipv6 != nil && ipv6.To4() == nil && addrs[0].IP.To4() != nil {
+		addrs = append(addrs, peer.ConnectionInfo{IP: ipv6, Port: uint16(port)})
+	}
+
+	peers, stats := s.Store.Announce(Announce{
+		InfoHash: infoHash,
+		PeerID:   peerID,
+		Addrs:    addrs,
+		Left:     left,
+		Event:    event,
+		NumWant:  numWant,
+	})
+	resp := announceResponse{
+		Interval:    int64(s.Interval / time.Second),
+		MinInterval: int64(s.MinInterval / time.Second),
+		Complete:    stats.Complete,
+		Incomplete:  stats.Incomplete,
+	}
+	if query.Get("compact") == "0" {
+		list := []dictionaryPeer{}
+		for _, p := range peers {
+			for _, addr := range p.Addrs {
+				list = append(list, dictionaryPeer{PeerID: p.ID[:], IP: addr.IP.String(), Port: addr.Port})
+			}
+		}
+		resp.Peers = list
+	} else {
+		var addrs []peer.ConnectionInfo
+		for _, p := range peers {
+			addrs = append(addrs, p.Addrs...)
+		}
+		ipv4, ipv6 := peer.EncodeConnectionInfo(addrs)
+		resp.Peers = append([]byte{}, ipv4...)
+		resp.Peers6 = ipv6
+	}
+	writeBencode(w, resp)
+}
+
+func (s *HTTPServer) scrape(w http.ResponseWriter, r *http.Request) {
+	var infoHashes [][20]byte
+	if values := r.URL.Query()["info_hash"]; len(values) > 0 {
+		for _, value := range values {
+			infoHash, ok := hashParam(value)
+			if !ok {
+				writeFailure(w, "invalid info_hash")
+				return
+			}
+			infoHashes = append(infoHashes, infoHash)
+		}
+	} else {
+		// A scrape without info hashes covers every swarm.
+		infoHashes = s.Store.InfoHashes()
+	}
+
+	resp := scrapeResponse{Files: make(map[string]scrapeFile)}
+	for _, infoHash := range infoHashes {
+		if !s.Allowlist.Allows(infoHash) {
+			continue
+		}
+		stats := s.Store.Scrape(infoHash)
+		resp.Files[string(infoHash[:])] = scrapeFile{
+			Complete:   stats.Complete,
+			Downloaded: stats.Downloaded,
+			Incomplete: stats.Incomplete,
+		}
+	}
+	writeBencode(w, resp)
+}
+
+// hashParam decodes a 20-byte info hash or peer ID from a query value.
+func hashParam(value string) ([20]byte, bool) {
+	if len(value) != 20 {
+		return [20]byte{}, false
+	}
+	return [20]byte([]byte(value)), true
+}
+
+func parseEvent(value string) (tracker.Event, bool) {
+	for _, event := range []tracker.Event{tracker.EventNone, tracker.EventStarted, tracker.EventCompleted, tracker.EventStopped} {
+		if event.String() == value {
+			return event, true
+		}
+	}
+	return tracker.EventNone, false
+}
+
+// writeFailure reports a rejected request the way clients expect: a failure reason in a
+// successful response.
+func writeFailure(w http.ResponseWriter, reason string) {
+	writeBencode(w, failureResponse{FailureReason: reason})
+}
+
+func writeBencode(w http.ResponseWriter, v interface{}) {
+	data, err := bencode.Marshal(v)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write(data)
+}
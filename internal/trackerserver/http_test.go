@@ -0,0 +1,153 @@
+package trackerserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/bencode"
+	"github.com/mattheworford/gotorrent/internal/peer"
+	"github.com/mattheworford/gotorrent/internal/tracker"
+)
+
+func TestHTTPServer(t *testing.T) {
+	allowed := [20]byte{1}
+	server := NewHTTPServer(NewStore(time.Hour))
+	server.Allowlist = Allowlist{allowed: {}}
+	server.MinInterval = time.Minute
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	client := tracker.NewHTTPTracker(httpServer.URL + "/announce")
+
+	seed := tracker.AnnounceRequest{InfoHash: allowed, PeerID: [20]byte{'s'}, Port: 1001, Event: tracker.EventStarted}
+	if _, err := client.Announce(context.Background(), seed); err != nil {
+		t.Fatalf("Announce failed: %v", err)
+	}
+
+	leecher := tracker.AnnounceRequest{
+		InfoHash: allowed,
+		PeerID:   [20]byte{'l'},
+		Port:     1002,
+		Left:     100,
+		Event:    tracker.EventStarted,
+		IPv6:     net.ParseIP("2001:db8::1"),
+	}
+	resp, err := client.Announce(context.Background(), leecher)
+	if err != nil {
+		t.Fatalf("Announce failed: %v", err)
+	}
+	expected := &tracker.AnnounceResponse{
+		Interval:    defaultInterval,
+		MinInterval: time.Minute,
+		Complete:    1,
+		Incomplete:  1,
+		Peers:       []peer.ConnectionInfo{{IP: net.IPv4(127, 0, 0, 1), Port: 1001}},
+	}
+	if !reflect.DeepEqual(resp, expected) {
+		t.Errorf("Unexpected response: got %+v, want %+v", resp, expected)
+	}
+
+	t.Run("IPv6Peers", func(t *testing.T) {
+		resp, err := client.Announce(context.Background(), seed)
+		if err != nil {
+			t.Fatalf("Announce failed: %v", err)
+		}
+		expected := []peer.ConnectionInfo{
+			{IP: net.IPv4(127, 0, 0, 1), Port: 1002},
+			{IP: net.ParseIP("2001:db8::1"), Port: 1002},
+		}
+		if !reflect.DeepEqual(resp.Peers, expected) {
+			t.Errorf("Unexpected peers: got %v, want %v", resp.Peers, expected)
+		}
+	})
+
+	t.Run("DictionaryPeers", func(t *testing.T) {
+		query := url.Values{}
+		query.Set("info_hash", string(allowed[:]))
+		query.Set("peer_id", string(seed.PeerID[:]))
+		query.Set("port", "1001")
+		query.Set("left", "0")
+		query.Set("compact", "0")
+		httpResp, err := http.Get(httpServer.URL + "/announce?" + query.Encode())
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer httpResp.Body.Close()
+		body, _ := io.ReadAll(httpResp.Body)
+		var decoded struct {
+			Peers []map[string]interface{} `bencode:"peers"`
+		}
+		if err := bencode.Unmarshal(body, &decoded); err != nil {
+			t.Fatalf("Failed to decode %q: %v", body, err)
+		}
+		if len(decoded.Peers) != 2 || decoded.Peers[0]["peer id"] != string(leecher.PeerID[:]) {
+			t.Errorf("Unexpected peers: %v", decoded.Peers)
+		}
+	})
+
+	t.Run("Scrape", func(t *testing.T) {
+		results, err := client.Scrape(context.Background(), [][20]byte{allowed, {2}})
+		if err != nil {
+			t.Fatalf("Scrape failed: %v", err)
+		}
+		expected := map[[20]byte]tracker.ScrapeResult{allowed: {Complete: 1, Incomplete: 1}}
+		if !reflect.DeepEqual(results, expected) {
+			t.Errorf("Unexpected results: got %+v, want %+v", results, expected)
+		}
+	})
+
+	t.Run("Stopped", func(t *testing.T) {
+		stopped := leecher
+		stopped.Event = tracker.EventStopped
+		resp, err := client.Announce(context.Background(), stopped)
+		if err != nil {
+			t.Fatalf("Announce failed: %v", err)
+		}
+		if resp.Incomplete != 0 || len(resp.Peers) != 0 {
+			t.Errorf("Unexpected response: %+v", resp)
+		}
+	})
+
+	testCases := []struct {
+		name     string
+		modify   func(*tracker.AnnounceRequest)
+		expected string
+	}{
+		{"NotAllowed", func(r *tracker.AnnounceRequest) { r.InfoHash = [20]byte{2} }, "unregistered torrent"},
+		{"ZeroPort", func(r *tracker.AnnounceRequest) { r.Port = 0 }, "invalid port"},
+		{"NegativeLeft", func(r *tracker.AnnounceRequest) { r.Left = -1 }, "invalid left"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := seed
+			tc.modify(&req)
+			_, err := client.Announce(context.Background(), req)
+			var failure *tracker.FailureError
+			if !errors.As(err, &failure) {
+				t.Fatalf("Unexpected error: got %v, want a *FailureError", err)
+			}
+			if failure.Reason != tc.expected {
+				t.Errorf("Unexpected reason: got %q, want %q", failure.Reason, tc.expected)
+			}
+		})
+	}
+
+	t.Run("NotFound", func(t *testing.T) {
+		httpResp, err := http.Get(httpServer.URL + "/other")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		httpResp.Body.Close()
+		if httpResp.StatusCode != http.StatusNotFound {
+			t.Errorf("Unexpected status: got %d, want %d", httpResp.StatusCode, http.StatusNotFound)
+		}
+	})
+}
@@ -0,0 +1,40 @@
+package trackerserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(2, 2)
+	now := time.Now()
+
+	steps := []struct {
+		name     string
+		key      string
+		advance  time.Duration
+		expected bool
+	}{
+		{"FirstOfBurst", "a", 0, true},
+		{"SecondOfBurst", "a", 0, true},
+		{"BurstSpent", "a", 0, false},
+		{"OtherKey", "b", 0, true},
+		{"PartialRefill", "a", 250 * time.Millisecond, false},
+		{"Refilled", "a", 250 * time.Millisecond, true},
+		{"RefillCappedAtBurst", "a", time.Hour, true},
+		{"AfterCap", "a", 0, true},
+		{"CapSpent", "a", 0, false},
+	}
+
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		if got := l.allow(step.key, now); got != step.expected {
+			t.Errorf("%s: got %v, want %v", step.name, got, step.expected)
+		}
+	}
+
+	l.sweep(now.Add(time.Hour))
+	if len(l.buckets) != 0 {
+		t.Errorf("Unexpected buckets after sweeping: got %d, want 0", len(l.buckets))
+	}
+}
@@ -0,0 +1,231 @@
+// Package trackerserver runs a BitTorrent tracker, keeping its swarms in memory.
+package trackerserver
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/peer"
+	"github.com/mattheworford/gotorrent/internal/tracker"
+)
+
+const (
+	defaultInterval = 30 * time.Minute
+	defaultNumWant  = 50
+	maxNumWant      = 200
+)
+
+// Allowlist restricts a tracker to known torrents. A nil Allowlist allows every torrent.
+type Allowlist map[[20]byte]struct{}
+
+// Allows tells if the tracker serves the torrent with the given info hash.
+func (a Allowlist) Allows(infoHash [20]byte) bool {
+	if a == nil {
+		return true
+	}
+	_, ok := a[infoHash]
+	return ok
+}
+
+// Peer is a member of a swarm as of its last announce. Addrs holds the addresses of the
+// latest announce over each family, so a dual-stack peer has both.
+type Peer struct {
+	ID    [20]byte
+	Addrs []peer.ConnectionInfo
+	Left  int64
+}
+
+// Announce is an announce received by a tracker, whatever its protocol.
+type Announce struct {
+	InfoHash [20]byte
+	PeerID   [20]byte
+	// Addrs are the addresses the peer accepts connections on, of either family.
+	Addrs   []peer.ConnectionInfo
+	Left    int64
+	Event   tracker.Event
+	NumWant int
+}
+
+// Store keeps the swarms of a tracker in memory. Peers that stop announcing are dropped
+// once their TTL passes. It is safe for concurrent use.
+type Store struct {
+	TTL time.Duration
+
+	now func() time.Time
+
+	mu     sync.Mutex
+	swarms map[[20]byte]*swarm
+}
+
+type swarm struct {
+	peers      map[[20]byte]*storedPeer
+	downloaded int
+}
+
+// storedPeer keeps the addresses of a peer by family, so that a dual-stack peer announcing
+// over IPv4 and IPv6 is listed under both. Each family expires on its own.
+type storedPeer struct {
+	left int64
+	ipv4 storedAddrs
+	ipv6 storedAddrs
+}
+
+type storedAddrs struct {
+	addrs   []peer.ConnectionInfo
+	expires time.Time
+}
+
+// NewStore creates an empty Store dropping peers that have not announced within ttl.
+func NewStore(ttl time.Duration) *Store {
+	return &Store{TTL: ttl, now: time.Now, swarms: make(map[[20]byte]*swarm)}
+}
+
+// Announce records an announce and returns up to NumWant other peers of the swarm in
+// random order, together with the swarm's statistics. Seeds are not sent other seeds.
+func (s *Store) Announce(a Announce) ([]Peer, tracker.ScrapeResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	sw := s.swarms[a.InfoHash]
+	if sw == nil {
+		if a.Event == tracker.EventStopped {
+			return nil, tracker.ScrapeResult{}
+		}
+		sw = &swarm{peers: make(map[[20]byte]*storedPeer)}
+		s.swarms[a.InfoHash] = sw
+	}
+	sw.expire(now)
+
+	switch a.Event {
+	case tracker.EventStopped:
+		delete(sw.peers, a.PeerID)
+	case tracker.EventCompleted:
+		if p, ok := sw.peers[a.PeerID]; !ok || p.left > 0 {
+			sw.downloaded++
+		}
+		fallthrough
+	default:
+		p := sw.peers[a.PeerID]
+		if p == nil {
+			p = &storedPeer{}
+			sw.peers[a.PeerID] = p
+		}
+		p.left = a.Left
+		p.update(a.Addrs, now.Add(s.TTL))
+	}
+	stats := sw.stats()
+	if len(sw.peers) == 0 {
+		delete(s.swarms, a.InfoHash)
+	}
+	if a.Event == tracker.EventStopped {
+		return nil, stats
+	}
+
+	numWant := a.NumWant
+	if numWant <= 0 {
+		numWant = defaultNumWant
+	}
+	numWant = min(numWant, maxNumWant)
+	var peers []Peer
+	for id, p := range sw.peers {
+		if id == a.PeerID || (a.Left == 0 && p.left == 0) {
+			continue
+		}
+		peers = append(peers, p.peer(id))
+	}
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+	if len(peers) > numWant {
+		peers = peers[:numWant]
+	}
+	return peers, stats
+}
+
+// Scrape returns the statistics of a swarm. Unknown torrents have empty statistics.
+func (s *Store) Scrape(infoHash [20]byte) tracker.ScrapeResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sw := s.swarms[infoHash]
+	if sw == nil {
+		return tracker.ScrapeResult{}
+	}
+	sw.expire(s.now())
+	return sw.stats()
+}
+
+// InfoHashes returns the info hashes of every swarm with peers.
+func (s *Store) InfoHashes() [][20]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	infoHashes := make([][20]byte, 0, len(s.swarms))
+	for infoHash := range s.swarms {
+		infoHashes = append(infoHashes, infoHash)
+	}
+	return infoHashes
+}
+
+// Sweep drops every expired peer, and the swarms left without peers.
+func (s *Store) Sweep() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for infoHash, sw := range s.swarms {
+		sw.expire(now)
+		if len(sw.peers) == 0 {
+			delete(s.swarms, infoHash)
+		}
+	}
+}
+
+func (sw *swarm) expire(now time.Time) {
+	for id, p := range sw.peers {
+		p.ipv4.expire(now)
+		p.ipv6.expire(now)
+		if p.ipv4.addrs == nil && p.ipv6.addrs == nil {
+			delete(sw.peers, id)
+		}
+	}
+}
+
+// update replaces the addresses of each family present in addrs, leaving the other alone.
+func (p *storedPeer) update(addrs []peer.ConnectionInfo, expires time.Time) {
+	var ipv4, ipv6 []peer.ConnectionInfo
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			ipv4 = append(ipv4, addr)
+		} else {
+			ipv6 = append(ipv6, addr)
+		}
+	}
+	if ipv4 != nil {
+		p.ipv4 = storedAddrs{addrs: ipv4, expires: expires}
+	}
+	if ipv6 != nil {
+		p.ipv6 = storedAddrs{addrs: ipv6, expires: expires}
+	}
+}
+
+// peer returns the peer with its addresses of both families.
+func (p *storedPeer) peer(id [20]byte) Peer {
+	addrs := make([]peer.ConnectionInfo, 0, len(p.ipv4.addrs)+len(p.ipv6.addrs))
+	addrs = append(append(addrs, p.ipv4.addrs...), p.ipv6.addrs...)
+	return Peer{ID: id, Addrs: addrs, Left: p.left}
+}
+
+func (a *storedAddrs) expire(now time.Time) {
+	if !now.Before(a.expires) {
+		*a = storedAddrs{}
+	}
+}
+
+func (sw *swarm) stats() tracker.ScrapeResult {
+	result := tracker.ScrapeResult{Downloaded: sw.downloaded}
+	for _, p := range sw.peers {
+		if p.left == 0 {
+			result.Complete++
+		} else {
+			result.Incomplete++
+		}
+	}
+	return result
+}
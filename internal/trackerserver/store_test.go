@@ -0,0 +1,146 @@
+package trackerserver
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/peer"
+	"github.com/mattheworford/gotorrent/internal/tracker"
+)
+
+func testAnnounce(id byte, left int64, event tracker.Event) Announce {
+	return Announce{
+		InfoHash: [20]byte{1},
+		PeerID:   [20]byte{id},
+		Addrs:    []peer.ConnectionInfo{{IP: net.IPv4(10, 0, 0, id), Port: 6881}},
+		Left:     left,
+		Event:    event,
+	}
+}
+
+func TestStoreAnnounce(t *testing.T) {
+	s := NewStore(time.Hour)
+
+	peers, stats := s.Announce(testAnnounce(1, 0, tracker.EventStarted))
+	if len(peers) != 0 {
+		t.Errorf("Unexpected peers for the first announce: %v", peers)
+	}
+	if stats != (tracker.ScrapeResult{Complete: 1}) {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	peers, stats = s.Announce(testAnnounce(2, 100, tracker.EventStarted))
+	if len(peers) != 1 || peers[0].ID != [20]byte{1} {
+		t.Errorf("Unexpected peers: %v", peers)
+	}
+	if stats != (tracker.ScrapeResult{Complete: 1, Incomplete: 1}) {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	t.Run("SeedsGetNoSeeds", func(t *testing.T) {
+		s.Announce(testAnnounce(3, 0, tracker.EventStarted))
+		peers, _ := s.Announce(testAnnounce(1, 0, tracker.EventNone))
+		if len(peers) != 1 || peers[0].ID != [20]byte{2} {
+			t.Errorf("Unexpected peers: %v", peers)
+		}
+	})
+
+	t.Run("NumWant", func(t *testing.T) {
+		a := testAnnounce(4, 100, tracker.EventStarted)
+		a.NumWant = 2
+		if peers, _ := s.Announce(a); len(peers) != 2 {
+			t.Errorf("Unexpected peer count: got %d, want 2", len(peers))
+		}
+	})
+
+	t.Run("Completed", func(t *testing.T) {
+		_, stats := s.Announce(testAnnounce(2, 0, tracker.EventCompleted))
+		if stats != (tracker.ScrapeResult{Complete: 3, Incomplete: 1, Downloaded: 1}) {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+		// A repeated completed event is not counted twice.
+		if _, stats := s.Announce(testAnnounce(2, 0, tracker.EventCompleted)); stats.Downloaded != 1 {
+			t.Errorf("Unexpected Downloaded: got %d, want 1", stats.Downloaded)
+		}
+	})
+
+	t.Run("Stopped", func(t *testing.T) {
+		peers, stats := s.Announce(testAnnounce(4, 100, tracker.EventStopped))
+		if peers != nil {
+			t.Errorf("Unexpected peers: %v", peers)
+		}
+		if stats != (tracker.ScrapeResult{Complete: 3, Downloaded: 1}) {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+	})
+}
+
+func TestStoreDualStack(t *testing.T) {
+	s := NewStore(time.Minute)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	ipv4 := peer.ConnectionInfo{IP: net.IPv4(10, 0, 0, 1), Port: 6881}
+	ipv6 := peer.ConnectionInfo{IP: net.ParseIP("2001:db8::1"), Port: 6881}
+	s.Announce(testAnnounce(1, 0, tracker.EventStarted))
+	now = now.Add(30 * time.Second)
+	a := testAnnounce(1, 0, tracker.EventNone)
+	a.Addrs = []peer.ConnectionInfo{ipv6}
+	s.Announce(a)
+
+	testCases := []struct {
+		name     string
+		advance  time.Duration
+		expected []peer.ConnectionInfo
+	}{
+		{"BothFamilies", 0, []peer.ConnectionInfo{ipv4, ipv6}},
+		{"IPv4Expired", 30 * time.Second, []peer.ConnectionInfo{ipv6}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			now = now.Add(tc.advance)
+			peers, _ := s.Announce(testAnnounce(2, 100, tracker.EventNone))
+			if len(peers) != 1 || !reflect.DeepEqual(peers[0].Addrs, tc.expected) {
+				t.Errorf("Unexpected peers: got %v, want addresses %v", peers, tc.expected)
+			}
+		})
+	}
+}
+
+func TestStoreExpiry(t *testing.T) {
+	s := NewStore(time.Minute)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	s.Announce(testAnnounce(1, 0, tracker.EventStarted))
+	now = now.Add(30 * time.Second)
+	s.Announce(testAnnounce(2, 100, tracker.EventStarted))
+	if got := s.Scrape([20]byte{1}); got != (tracker.ScrapeResult{Complete: 1, Incomplete: 1}) {
+		t.Errorf("Unexpected stats: %+v", got)
+	}
+
+	now = now.Add(30 * time.Second)
+	if got := s.Scrape([20]byte{1}); got != (tracker.ScrapeResult{Incomplete: 1}) {
+		t.Errorf("Unexpected stats after the seed expired: %+v", got)
+	}
+
+	now = now.Add(30 * time.Second)
+	s.Sweep()
+	if got := s.InfoHashes(); len(got) != 0 {
+		t.Errorf("Unexpected swarms after sweeping: %x", got)
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	var all Allowlist
+	if !all.Allows([20]byte{1}) {
+		t.Error("Expected a nil allowlist to allow every torrent")
+	}
+	allowlist := Allowlist{[20]byte{1}: {}}
+	if !allowlist.Allows([20]byte{1}) || allowlist.Allows([20]byte{2}) {
+		t.Error("Expected the allowlist to allow only its torrents")
+	}
+}
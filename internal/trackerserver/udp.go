@@ -0,0 +1,209 @@
+package trackerserver
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/peer"
+	"github.com/mattheworford/gotorrent/internal/tracker"
+)
+
+const (
+	udpProtocolID = 0x41727101980
+
+	actionConnect  = 0
+	actionAnnounce = 1
+	actionScrape   = 2
+	actionError    = 3
+
+	// connectionIDEpoch is how often connection IDs change; an ID stays valid for the
+	// epoch it was issued in and the next, so for one to two minutes.
+	connectionIDEpoch = time.Minute
+	maxScrapeHashes   = 74
+
+	defaultRateLimit = 10 // requests per second
+	defaultRateBurst = 20
+)
+
+// UDPServer serves the UDP tracker protocol (BEP 15). Connection IDs are keyed hashes of
+// the client address and the current epoch, so no per-client state is kept for them.
+// Requests beyond the per-address rate limit are dropped without a reply.
+type UDPServer struct {
+	Store     *Store
+	Allowlist Allowlist
+	Interval  time.Duration
+
+	key     []byte
+	limiter *rateLimiter
+	now     func() time.Time
+}
+
+// NewUDPServer creates a UDPServer for the swarms of a store, allowing each client address
+// rate requests per second with bursts of burst requests.
+func NewUDPServer(store *Store, rate float64, burst int) (*UDPServer, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("trackerserver: failed to generate key: %w", err)
+	}
+	if rate <= 0 {
+		rate, burst = defaultRateLimit, defaultRateBurst
+	}
+	return &UDPServer{
+		Store:    store,
+		Interval: defaultInterval,
+		key:      key,
+		limiter:  newRateLimiter(rate, max(burst, 1)),
+		now:      time.Now,
+	}, nil
+}
+
+// Serve answers requests arriving on conn until reading from it fails, as it does once
+// conn is closed.
+func (s *UDPServer) Serve(conn net.PacketConn) error {
+	buf := make([]byte, 2048)
+	for {
+		size, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("trackerserver: failed to read request: %w", err)
+		}
+		udpAddr, ok := addr.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		if reply := s.handle(buf[:size], udpAddr); reply != nil {
+			conn.WriteTo(reply, addr)
+		}
+	}
+}
+
+// handle returns the reply to a request, or nil when none should be sent.
+func (s *UDPServer) handle(packet []byte, addr *net.UDPAddr) []byte {
+	if len(packet) < 16 {
+		return nil
+	}
+	now := s.now()
+	if !s.limiter.allow(addr.IP.String(), now) {
+		return nil
+	}
+	action := binary.BigEndian.Uint32(packet[8:12])
+	tid := packet[12:16]
+
+	if action == actionConnect {
+		if binary.BigEndian.Uint64(packet[0:8]) != udpProtocolID {
+			return nil
+		}
+		return reply(actionConnect, tid, binary.BigEndian.AppendUint64(nil, s.connectionID(addr.IP, now, 0)))
+	}
+	if !s.validConnectionID(binary.BigEndian.Uint64(packet[0:8]), addr.IP, now) {
+		return reply(actionError, tid, []byte("invalid connection id"))
+	}
+	switch action {
+	case actionAnnounce:
+		return s.announce(packet, tid, addr)
+	case actionScrape:
+		return s.scrape(packet, tid)
+	}
+	return reply(actionError, tid, []byte("unknown action"))
+}
+
+func (s *UDPServer) announce(packet, tid []byte, addr *net.UDPAddr) []byte {
+	if len(packet) < 98 {
+		return reply(actionError, tid, []byte("malformed announce"))
+	}
+	infoHash := [20]byte(packet[16:36])
+	if !s.Allowlist.Allows(infoHash) {
+		return reply(actionError, tid, []byte("unregistered torrent"))
+	}
+	event := tracker.Event(binary.BigEndian.Uint32(packet[80:84]))
+	if event > tracker.EventStopped {
+		return reply(actionError, tid, []byte("invalid event"))
+	}
+	port := binary.BigEndian.Uint16(packet[96:98])
+	if port == 0 {
+		return reply(actionError, tid, []byte("invalid port"))
+	}
+	left := int64(binary.BigEndian.Uint64(packet[64:72]))
+	if left < 0 {
+		return reply(actionError, tid, []byte("invalid left"))
+	}
+
+	peers, stats := s.Store.Announce(Announce{
+		InfoHash: infoHash,
+		PeerID:   [20]byte(packet[36:56]),
+		Addrs:    []peer.ConnectionInfo{{IP: addr.IP, Port: port}},
+		Left:     left,
+		Event:    event,
+		NumWant:  int(int32(binary.BigEndian.Uint32(packet[92:96]))),
+	})
+
+	// Peers are returned in the address family the request arrived in.
+	var addrs []peer.ConnectionInfo
+	for _, p := range peers {
+		addrs = append(addrs, p.Addrs...)
+	}
+	ipv4, ipv6 := peer.EncodeConnectionInfo(addrs)
+	compact := ipv4
+	if addr.IP.To4() == nil {
+		compact = ipv6
+	}
+
+	body := binary.BigEndian.AppendUint32(nil, uint32(s.Interval/time.Second))
+	body = binary.BigEndian.AppendUint32(body, uint32(stats.Incomplete))
+	body = binary.BigEndian.AppendUint32(body, uint32(stats.Complete))
+	return reply(actionAnnounce, tid, append(body, compact...))
+}
+
+func (s *UDPServer) scrape(packet, tid []byte) []byte {
+	hashes := packet[16:]
+	if len(hashes) == 0 || len(hashes)%20 != 0 || len(hashes)/20 > maxScrapeHashes {
+		return reply(actionError, tid, []byte("malformed scrape"))
+	}
+	var body []byte
+	for ; len(hashes) > 0; hashes = hashes[20:] {
+		// Torrents outside the allowlist look like empty swarms.
+		var stats tracker.ScrapeResult
+		if infoHash := [20]byte(hashes[:20]); s.Allowlist.Allows(infoHash) {
+			stats = s.Store.Scrape(infoHash)
+		}
+		body = binary.BigEndian.AppendUint32(body, uint32(stats.Complete))
+		body = binary.BigEndian.AppendUint32(body, uint32(stats.Downloaded))
+		body = binary.BigEndian.AppendUint32(body, uint32(stats.Incomplete))
+	}
+	return reply(actionScrape, tid, body)
+}
+
+// connectionID derives the connection ID of a client address for the epoch containing now,
+// or for an earlier one when age is positive.
+func (s *UDPServer) connectionID(ip net.IP, now time.Time, age int64) uint64 {
+	epoch := now.UnixNano()/int64(connectionIDEpoch) - age
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(binary.BigEndian.AppendUint64(nil, uint64(epoch)))
+	mac.Write(ip.To16())
+	return binary.BigEndian.Uint64(mac.Sum(nil))
+}
+
+// validConnectionID tells if id was issued to the address in this epoch or the previous one.
+func (s *UDPServer) validConnectionID(id uint64, ip net.IP, now time.Time) bool {
+	got := binary.BigEndian.AppendUint64(nil, id)
+	valid := false
+	for age := int64(0); age <= 1; age++ {
+		expected := binary.BigEndian.AppendUint64(nil, s.connectionID(ip, now, age))
+		valid = hmac.Equal(got, expected) || valid
+	}
+	return valid
+}
+
+func reply(action uint32, tid, body []byte) []byte {
+	packet := binary.BigEndian.AppendUint32(nil, action)
+	packet = append(packet, tid...)
+	return append(packet, body...)
+}
@@ -0,0 +1,235 @@
+package trackerserver
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/peer"
+	"github.com/mattheworford/gotorrent/internal/tracker"
+)
+
+// startUDPServer serves a UDPServer on a loopback address, calling configure before serving.
+func startUDPServer(t *testing.T, address string, rate float64, burst int, configure func(*UDPServer)) net.Addr {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		t.Skipf("Failed to listen on %s: %v", address, err)
+	}
+	server, err := NewUDPServer(NewStore(time.Hour), rate, burst)
+	if err != nil {
+		t.Fatalf("NewUDPServer failed: %v", err)
+	}
+	if configure != nil {
+		configure(server)
+	}
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(conn) }()
+	t.Cleanup(func() {
+		conn.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Serve failed: %v", err)
+		}
+	})
+	return conn.LocalAddr()
+}
+
+func udpClient(t *testing.T, addr net.Addr) *tracker.UDPTracker {
+	t.Helper()
+	client, err := tracker.NewUDPTracker("udp://" + addr.String())
+	if err != nil {
+		t.Fatalf("NewUDPTracker failed: %v", err)
+	}
+	client.Timeout = 100 * time.Millisecond
+	client.MaxRetries = 2
+	return client
+}
+
+func TestUDPServerEndToEnd(t *testing.T) {
+	addr := startUDPServer(t, "127.0.0.1:0", 0, 0, func(s *UDPServer) {
+		s.Allowlist = Allowlist{{1}: {}}
+		s.Interval = 5 * time.Minute
+	})
+	client := udpClient(t, addr)
+
+	seed := tracker.AnnounceRequest{InfoHash: [20]byte{1}, PeerID: [20]byte{'s'}, Port: 1001, Event: tracker.EventStarted}
+	if _, err := client.Announce(context.Background(), seed); err != nil {
+		t.Fatalf("Announce failed: %v", err)
+	}
+	leecher := tracker.AnnounceRequest{InfoHash: [20]byte{1}, PeerID: [20]byte{'l'}, Port: 1002, Left: 10, Event: tracker.EventStarted}
+	resp, err := client.Announce(context.Background(), leecher)
+	if err != nil {
+		t.Fatalf("Announce failed: %v", err)
+	}
+	expected := &tracker.AnnounceResponse{
+		Interval:   5 * time.Minute,
+		Complete:   1,
+		Incomplete: 1,
+		Peers:      []peer.ConnectionInfo{{IP: net.IPv4(127, 0, 0, 1), Port: 1001}},
+	}
+	if !reflect.DeepEqual(resp, expected) {
+		t.Errorf("Unexpected response: got %+v, want %+v", resp, expected)
+	}
+
+	t.Run("Scrape", func(t *testing.T) {
+		results, err := client.Scrape(context.Background(), [][20]byte{{1}, {2}})
+		if err != nil {
+			t.Fatalf("Scrape failed: %v", err)
+		}
+		expected := map[[20]byte]tracker.ScrapeResult{{1}: {Complete: 1, Incomplete: 1}, {2}: {}}
+		if !reflect.DeepEqual(results, expected) {
+			t.Errorf("Unexpected results: got %+v, want %+v", results, expected)
+		}
+	})
+
+	t.Run("NotAllowed", func(t *testing.T) {
+		req := seed
+		req.InfoHash = [20]byte{2}
+		_, err := client.Announce(context.Background(), req)
+		var failure *tracker.FailureError
+		if !errors.As(err, &failure) || failure.Reason != "unregistered torrent" {
+			t.Errorf("Unexpected error: got %v, want a *FailureError", err)
+		}
+	})
+}
+
+func TestUDPServerIPv6(t *testing.T) {
+	addr := startUDPServer(t, "[::1]:0", 0, 0, nil)
+	client := udpClient(t, addr)
+
+	seed := tracker.AnnounceRequest{InfoHash: [20]byte{1}, PeerID: [20]byte{'s'}, Port: 1001}
+	if _, err := client.Announce(context.Background(), seed); err != nil {
+		t.Fatalf("Announce failed: %v", err)
+	}
+	resp, err := client.Announce(context.Background(), tracker.AnnounceRequest{InfoHash: [20]byte{1}, PeerID: [20]byte{'l'}, Port: 1002, Left: 1})
+	if err != nil {
+		t.Fatalf("Announce failed: %v", err)
+	}
+	expected := []peer.ConnectionInfo{{IP: net.IPv6loopback, Port: 1001}}
+	if !reflect.DeepEqual(resp.Peers, expected) {
+		t.Errorf("Unexpected peers: got %v, want %v", resp.Peers, expected)
+	}
+}
+
+// exchange sends one raw request and returns the reply, or nil if none arrives in time.
+func exchange(t *testing.T, conn net.Conn, packet []byte) []byte {
+	t.Helper()
+	if _, err := conn.Write(packet); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	buf := make([]byte, 2048)
+	size, err := conn.Read(buf)
+	if err != nil {
+		return nil
+	}
+	return buf[:size]
+}
+
+func connectPacket() []byte {
+	packet := binary.BigEndian.AppendUint64(nil, udpProtocolID)
+	packet = binary.BigEndian.AppendUint32(packet, actionConnect)
+	return binary.BigEndian.AppendUint32(packet, 42)
+}
+
+func TestUDPServerRawRequests(t *testing.T) {
+	addr := startUDPServer(t, "127.0.0.1:0", 0, 0, nil)
+	conn, err := net.Dial("udp", addr.String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	connected := exchange(t, conn, connectPacket())
+	if len(connected) != 16 || binary.BigEndian.Uint32(connected[4:8]) != 42 {
+		t.Fatalf("Unexpected connect reply: %x", connected)
+	}
+	connectionID := connected[8:16]
+
+	testCases := []struct {
+		name     string
+		packet   []byte
+		expected string
+	}{
+		{"InvalidConnectionID", append(make([]byte, 8), 0, 0, 0, 1, 0, 0, 0, 7), "invalid connection id"},
+		{"UnknownAction", append(append([]byte{}, connectionID...), 0, 0, 0, 9, 0, 0, 0, 7), "unknown action"},
+		{"ShortAnnounce", append(append([]byte{}, connectionID...), 0, 0, 0, 1, 0, 0, 0, 7), "malformed announce"},
+		{"MalformedScrape", append(append([]byte{}, connectionID...), 0, 0, 0, 2, 0, 0, 0, 7, 1, 2, 3), "malformed scrape"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := exchange(t, conn, tc.packet)
+			if len(got) < 8 || binary.BigEndian.Uint32(got[0:4]) != actionError || binary.BigEndian.Uint32(got[4:8]) != 7 {
+				t.Fatalf("Unexpected reply: %x", got)
+			}
+			if string(got[8:]) != tc.expected {
+				t.Errorf("Unexpected message: got %q, want %q", got[8:], tc.expected)
+			}
+		})
+	}
+
+	t.Run("WrongProtocolID", func(t *testing.T) {
+		packet := connectPacket()
+		packet[0] = 0xff
+		if got := exchange(t, conn, packet); got != nil {
+			t.Errorf("Unexpected reply: %x", got)
+		}
+	})
+}
+
+func TestUDPServerRateLimit(t *testing.T) {
+	addr := startUDPServer(t, "127.0.0.1:0", 0.001, 3, nil)
+	conn, err := net.Dial("udp", addr.String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	for i := 1; i <= 4; i++ {
+		got := exchange(t, conn, connectPacket())
+		if allowed := i <= 3; (got != nil) != allowed {
+			t.Errorf("Unexpected reply to request %d: got %x, want reply %v", i, got, allowed)
+		}
+	}
+}
+
+func TestUDPServerConnectionID(t *testing.T) {
+	server, err := NewUDPServer(NewStore(time.Hour), 0, 0)
+	if err != nil {
+		t.Fatalf("NewUDPServer failed: %v", err)
+	}
+	ip := net.IPv4(10, 0, 0, 1)
+	issued := time.Unix(0, 0).Add(100*connectionIDEpoch + connectionIDEpoch/2)
+	id := server.connectionID(ip, issued, 0)
+
+	testCases := []struct {
+		name     string
+		ip       net.IP
+		at       time.Time
+		expected bool
+	}{
+		{"SameEpoch", ip, issued, true},
+		{"IPv4Mapped", net.ParseIP("::ffff:10.0.0.1"), issued, true},
+		{"NextEpoch", ip, issued.Add(connectionIDEpoch), true},
+		{"TwoEpochsLater", ip, issued.Add(2 * connectionIDEpoch), false},
+		{"OtherAddress", net.IPv4(10, 0, 0, 2), issued, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := server.validConnectionID(id, tc.ip, tc.at); got != tc.expected {
+				t.Errorf("Unexpected validity: got %v, want %v", got, tc.expected)
+			}
+		})
+	}
+
+	other, _ := NewUDPServer(NewStore(time.Hour), 0, 0)
+	if other.validConnectionID(id, ip, issued) {
+		t.Error("Expected a connection ID to be invalid with another key")
+	}
+}
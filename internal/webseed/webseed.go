@@ -0,0 +1,226 @@
+// Package webseed downloads pieces from BEP 19 web seeds, plain HTTP servers hosting the
+// files of a torrent, backing off from seeds that fail.
+package webseed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/torrentdata"
+)
+
+const (
+	defaultMinBackoff = 5 * time.Second
+	defaultMaxBackoff = 10 * time.Minute
+)
+
+// ErrBackoff is returned while the web seed is backing off after a failed request.
+var ErrBackoff = errors.New("webseed: backing off after previous failure")
+
+// Client downloads pieces from a BEP 19 (GetRight-style) web seed.
+// It is safe for concurrent use.
+type Client struct {
+	URL        string
+	HTTPClient *http.Client
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	torrent *torrentdata.TorrentData
+	now     func() time.Time
+
+	mu          sync.Mutex
+	failures    int
+	nextAttempt time.Time
+}
+
+// NewClient creates a Client downloading the pieces of a torrent from the web seed at rawURL.
+func NewClient(rawURL string, torrent *torrentdata.TorrentData) *Client {
+	return &Client{
+		URL:        rawURL,
+		HTTPClient: http.DefaultClient,
+		MinBackoff: defaultMinBackoff,
+		MaxBackoff: defaultMaxBackoff,
+		torrent:    torrent,
+		now:        time.Now,
+	}
+}
+
+// FileURL returns the URL of the file at the given index. For single-file torrents a URL
+// ending in a slash is completed with the torrent name; for multi-file torrents the root
+// directory and path components are always appended.
+func (c *Client) FileURL(fileIndex int) (string, error) {
+	if fileIndex < 0 || fileIndex >= len(c.torrent.Files) {
+		return "", fmt.Errorf("webseed: file index %d out of range", fileIndex)
+	}
+	if !c.torrent.IsMultiFile() && !strings.HasSuffix(c.URL, "/") {
+		return c.URL, nil
+	}
+	base := c.URL
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	components := c.torrent.Files[fileIndex].Path
+	escaped := make([]string, len(components))
+	for i, component := range components {
+		escaped[i] = url.PathEscape(component)
+	}
+	return base + strings.Join(escaped, "/"), nil
+}
+
+// DownloadPiece fetches the piece at the given index with HTTP range requests and verifies
+// it against the torrent's piece hash. Failures of the web seed, such as transport errors,
+// unexpected statuses and corrupt pieces, put the client into exponential backoff.
+func (c *Client) DownloadPiece(ctx context.Context, index int) ([]byte, error) {
+	if err := c.checkBackoff(); err != nil {
+		return nil, err
+	}
+	piece, err := c.downloadPiece(ctx, index)
+	c.recordResult(err)
+	if err != nil {
+		return nil, err
+	}
+	return piece, nil
+}
+
+func (c *Client) downloadPiece(ctx context.Context, index int) ([]byte, error) {
+	size, err := c.torrent.PieceSize(index)
+	if err != nil {
+		return nil, err
+	}
+	spans, err := c.torrent.PieceSpans(index)
+	if err != nil {
+		return nil, err
+	}
+
+	piece := make([]byte, size)
+	for _, span := range spans {
+		// Padding files are never hosted by web seeds; their contents are zeros.
+		if c.torrent.Files[span.FileIndex].Padding {
+			continue
+		}
+		buf := piece[span.PieceOffset : span.PieceOffset+span.Length]
+		if err := c.fetchRange(ctx, span, buf); err != nil {
+			return nil, err
+		}
+	}
+
+	ok, err := c.torrent.VerifyPiece(index, piece)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &seedError{fmt.Errorf("webseed: piece %d failed hash check", index)}
+	}
+	return piece, nil
+}
+
+// fetchRange reads the bytes of a file span into buf.
+func (c *Client) fetchRange(ctx context.Context, span torrentdata.FileSpan, buf []byte) error {
+	fileURL, err := c.FileURL(span.FileIndex)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return fmt.Errorf("webseed: failed to create request: %w", err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", span.FileOffset, span.FileOffset+span.Length-1))
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return &seedError{fmt.Errorf("webseed: request failed: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// The server ignored the range; only usable when the span covers the whole file.
+		if span.FileOffset != 0 || span.Length != c.torrent.Files[span.FileIndex].Length {
+			return &seedError{fmt.Errorf("webseed: server at %s does not support range requests", fileURL)}
+		}
+	case http.StatusServiceUnavailable:
+		if retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && retryAfter > 0 {
+			return &retryError{delay: time.Duration(retryAfter) * time.Second}
+		}
+		return &seedError{fmt.Errorf("webseed: unexpected status %s from %s", resp.Status, fileURL)}
+	default:
+		return &seedError{fmt.Errorf("webseed: unexpected status %s from %s", resp.Status, fileURL)}
+	}
+
+	if _, err := io.ReadFull(resp.Body, buf); err != nil {
+		return &seedError{fmt.Errorf("webseed: failed to read response body: %w", err)}
+	}
+	return nil
+}
+
+// seedError marks a failure of the web seed itself, as opposed to a bad request from the
+// caller, so that only the former puts the client into backoff.
+type seedError struct {
+	err error
+}
+
+func (e *seedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *seedError) Unwrap() error {
+	return e.err
+}
+
+// retryError reports a server-requested delay before the next attempt.
+type retryError struct {
+	delay time.Duration
+}
+
+func (e *retryError) Error() string {
+	return fmt.Sprintf("webseed: server busy, retry after %s", e.delay)
+}
+
+func (c *Client) checkBackoff() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.now().Before(c.nextAttempt) {
+		return ErrBackoff
+	}
+	return nil
+}
+
+// recordResult resets the backoff after a success, or doubles it after a failure of the seed.
+func (c *Client) recordResult(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		c.failures = 0
+		c.nextAttempt = time.Time{}
+		return
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	var seedErr *seedError
+	var retry *retryError
+	isRetry := errors.As(err, &retry)
+	if !isRetry && !errors.As(err, &seedErr) {
+		return
+	}
+
+	c.failures++
+	delay := c.MinBackoff
+	for i := 1; i < c.failures && delay < c.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, c.MaxBackoff)
+	if isRetry {
+		delay = max(delay, retry.delay)
+	}
+	c.nextAttempt = c.now().Add(delay)
+}
@@ -0,0 +1,235 @@
+package webseed
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/torrentdata"
+)
+
+const pieceLength = 32
+
+func pieceHashes(data []byte) [][20]byte {
+	var hashes [][20]byte
+	for begin := 0; begin < len(data); begin += pieceLength {
+		hashes = append(hashes, sha1.Sum(data[begin:min(begin+pieceLength, len(data))]))
+	}
+	return hashes
+}
+
+// serveFiles serves the given files by URL path with range support and counts requests.
+func serveFiles(t *testing.T, files map[string][]byte, requests *int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, r.URL.Path, time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFileURL(t *testing.T) {
+	single := &torrentdata.TorrentData{Name: "image.iso", Files: []torrentdata.File{{Path: []string{"image.iso"}}}}
+	multi := &torrentdata.TorrentData{Name: "root", Files: []torrentdata.File{{Path: []string{"root", "sub dir", "a.txt"}}}}
+
+	testCases := []struct {
+		name     string
+		url      string
+		torrent  *torrentdata.TorrentData
+		expected string
+	}{
+		{"SingleFileExactURL", "http://mirror/files/image-latest.iso", single, "http://mirror/files/image-latest.iso"},
+		{"SingleFileDirectoryURL", "http://mirror/files/", single, "http://mirror/files/image.iso"},
+		{"MultiFile", "http://mirror/files", multi, "http://mirror/files/root/sub%20dir/a.txt"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewClient(tc.url, tc.torrent).FileURL(0)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("Unexpected URL: got %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestDownloadPiece(t *testing.T) {
+	t.Run("SingleFile", func(t *testing.T) {
+		data := []byte(strings.Repeat("0123456789", 10))
+		var requests int
+		server := serveFiles(t, map[string][]byte{"/image.iso": data}, &requests)
+		torrent := &torrentdata.TorrentData{
+			Name:        "image.iso",
+			PieceHashes: pieceHashes(data),
+			PieceLength: pieceLength,
+			Length:      len(data),
+			Files:       []torrentdata.File{{Path: []string{"image.iso"}, Length: len(data)}},
+		}
+		client := NewClient(server.URL+"/", torrent)
+
+		for index := range torrent.PieceHashes {
+			piece, err := client.DownloadPiece(context.Background(), index)
+			if err != nil {
+				t.Fatalf("DownloadPiece(%d) failed: %v", index, err)
+			}
+			expected := data[index*pieceLength : min((index+1)*pieceLength, len(data))]
+			if !bytes.Equal(piece, expected) {
+				t.Errorf("Unexpected piece %d: got %q, want %q", index, piece, expected)
+			}
+		}
+	})
+
+	t.Run("MultiFileWithPadding", func(t *testing.T) {
+		a := []byte(strings.Repeat("a", 20))
+		b := []byte(strings.Repeat("b", 40))
+		data := append(append(append([]byte{}, a...), make([]byte, 12)...), b...)
+		var requests int
+		server := serveFiles(t, map[string][]byte{"/root/a.txt": a, "/root/b.txt": b}, &requests)
+		torrent := &torrentdata.TorrentData{
+			Name:        "root",
+			PieceHashes: pieceHashes(data),
+			PieceLength: pieceLength,
+			Length:      len(data),
+			Files: []torrentdata.File{
+				{Path: []string{"root", "a.txt"}, Length: 20, Offset: 0},
+				{Path: []string{"root", ".pad", "12"}, Length: 12, Offset: 20, Padding: true},
+				{Path: []string{"root", "b.txt"}, Length: 40, Offset: 32},
+			},
+		}
+		client := NewClient(server.URL, torrent)
+
+		piece, err := client.DownloadPiece(context.Background(), 0)
+		if err != nil {
+			t.Fatalf("DownloadPiece failed: %v", err)
+		}
+		if !bytes.Equal(piece, data[:pieceLength]) {
+			t.Errorf("Unexpected piece: got %q, want %q", piece, data[:pieceLength])
+		}
+		if requests != 1 {
+			t.Errorf("Expected padding to be skipped, got %d requests", requests)
+		}
+	})
+
+	t.Run("HashMismatchBacksOff", func(t *testing.T) {
+		data := []byte(strings.Repeat("x", pieceLength))
+		var requests int
+		server := serveFiles(t, map[string][]byte{"/file": []byte(strings.Repeat("y", pieceLength))}, &requests)
+		torrent := &torrentdata.TorrentData{
+			Name:        "file",
+			PieceHashes: pieceHashes(data),
+			PieceLength: pieceLength,
+			Length:      len(data),
+			Files:       []torrentdata.File{{Path: []string{"file"}, Length: len(data)}},
+		}
+		now := time.Unix(1700000000, 0)
+		client := NewClient(server.URL+"/file", torrent)
+		client.now = func() time.Time { return now }
+
+		if _, err := client.DownloadPiece(context.Background(), 0); err == nil {
+			t.Fatal("Expected error, got nil")
+		} else if err.Error() != "webseed: piece 0 failed hash check" {
+			t.Errorf("Unexpected error message: got %q, want %q", err.Error(), "webseed: piece 0 failed hash check")
+		}
+		if _, err := client.DownloadPiece(context.Background(), 0); !errors.Is(err, ErrBackoff) {
+			t.Errorf("Unexpected error: got %v, want %v", err, ErrBackoff)
+		}
+		if requests != 1 {
+			t.Errorf("Expected no request during backoff, got %d requests", requests)
+		}
+
+		now = now.Add(defaultMinBackoff)
+		client.DownloadPiece(context.Background(), 0)
+		if requests != 2 {
+			t.Errorf("Expected a retry after the backoff, got %d requests", requests)
+		}
+		if expected := now.Add(2 * defaultMinBackoff); !client.nextAttempt.Equal(expected) {
+			t.Errorf("Unexpected next attempt: got %v, want %v", client.nextAttempt, expected)
+		}
+	})
+
+	t.Run("CallerErrorDoesNotBackOff", func(t *testing.T) {
+		data := []byte(strings.Repeat("x", pieceLength))
+		var requests int
+		server := serveFiles(t, map[string][]byte{"/file": data}, &requests)
+		torrent := &torrentdata.TorrentData{
+			Name:        "file",
+			PieceHashes: pieceHashes(data),
+			PieceLength: pieceLength,
+			Length:      len(data),
+			Files:       []torrentdata.File{{Path: []string{"file"}, Length: len(data)}},
+		}
+		client := NewClient(server.URL+"/file", torrent)
+
+		if _, err := client.DownloadPiece(context.Background(), 5); err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := client.DownloadPiece(ctx, 0); !errors.Is(err, context.Canceled) {
+			t.Errorf("Unexpected error: got %v, want %v", err, context.Canceled)
+		}
+		if _, err := client.DownloadPiece(context.Background(), 0); err != nil {
+			t.Errorf("DownloadPiece failed after caller errors: %v", err)
+		}
+	})
+
+	t.Run("RetryAfter", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+		torrent := &torrentdata.TorrentData{
+			Name:        "file",
+			PieceHashes: make([][20]byte, 1),
+			PieceLength: pieceLength,
+			Length:      pieceLength,
+			Files:       []torrentdata.File{{Path: []string{"file"}, Length: pieceLength}},
+		}
+		now := time.Unix(1700000000, 0)
+		client := NewClient(server.URL+"/file", torrent)
+		client.now = func() time.Time { return now }
+
+		if _, err := client.DownloadPiece(context.Background(), 0); err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+		if expected := now.Add(120 * time.Second); !client.nextAttempt.Equal(expected) {
+			t.Errorf("Unexpected next attempt: got %v, want %v", client.nextAttempt, expected)
+		}
+	})
+
+	t.Run("RangeNotSupported", func(t *testing.T) {
+		data := []byte(strings.Repeat("z", 2*pieceLength))
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(data)
+		}))
+		defer server.Close()
+		torrent := &torrentdata.TorrentData{
+			Name:        "file",
+			PieceHashes: pieceHashes(data),
+			PieceLength: pieceLength,
+			Length:      len(data),
+			Files:       []torrentdata.File{{Path: []string{"file"}, Length: len(data)}},
+		}
+
+		_, err := NewClient(server.URL+"/file", torrent).DownloadPiece(context.Background(), 1)
+		if err == nil || !strings.Contains(err.Error(), "does not support range requests") {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/torrentdata"
+	"github.com/mattheworford/gotorrent/internal/tracker"
+)
+
+func runScrape(args []string) error {
+	fs := flag.NewFlagSet("scrape", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 15*time.Second, "time allowed for each tracker to answer")
+	proxyURL := fs.String("proxy", "", "reach trackers through this http:// or socks5:// proxy, with optional user:password@ credentials")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: gotorrent scrape [flags] <file.torrent|url>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("expected at least one torrent file or URL")
+	}
+	var proxy *tracker.Proxy
+	if *proxyURL != "" {
+		var err error
+		if proxy, err = tracker.ParseProxy(*proxyURL); err != nil {
+			return err
+		}
+	}
+
+	// Each tracker is scraped once for all of the torrents it serves.
+	var trackerURLs []string
+	torrentsByTracker := make(map[string][]*torrentdata.TorrentData)
+	for _, source := range fs.Args() {
+		torrent, err := loadTorrent(source)
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		for _, trackerURL := range torrentTrackers(torrent) {
+			if _, ok := torrentsByTracker[trackerURL]; !ok {
+				trackerURLs = append(trackerURLs, trackerURL)
+			}
+			torrentsByTracker[trackerURL] = append(torrentsByTracker[trackerURL], torrent)
+		}
+	}
+	if len(trackerURLs) == 0 {
+		return errors.New("no trackers to scrape")
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TRACKER\tSEEDERS\tLEECHERS\tDOWNLOADED\tNAME")
+	failures := 0
+	for _, trackerURL := range trackerURLs {
+		torrents := torrentsByTracker[trackerURL]
+		results, err := scrapeTracker(trackerURL, torrents, proxy, *timeout)
+		if err != nil {
+			failures++
+			fmt.Fprintf(os.Stderr, "gotorrent scrape: %s: %v\n", trackerURL, err)
+			continue
+		}
+		for _, torrent := range torrents {
+			result, ok := results[torrent.SwarmHashes()[0]]
+			if !ok {
+				fmt.Fprintf(tw, "%s\t-\t-\t-\t%s\n", trackerURL, torrent.Name)
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\n", trackerURL, result.Complete, result.Incomplete, result.Downloaded, torrent.Name)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if failures == len(trackerURLs) {
+		return errors.New("every tracker failed")
+	}
+	return nil
+}
+
+// scrapeTracker fetches the swarm statistics of the torrents from one tracker, through
+// proxy unless it is nil.
+func scrapeTracker(trackerURL string, torrents []*torrentdata.TorrentData, proxy *tracker.Proxy, timeout time.Duration) (map[[20]byte]tracker.ScrapeResult, error) {
+	t, err := tracker.NewWithProxy(trackerURL, proxy)
+	if err != nil {
+		return nil, err
+	}
+	scraper, ok := t.(tracker.Scraper)
+	if !ok {
+		return nil, tracker.ErrScrapeUnsupported
+	}
+	infoHashes := make([][20]byte, len(torrents))
+	for i, torrent := range torrents {
+		infoHashes[i] = torrent.SwarmHashes()[0]
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return scraper.Scrape(ctx, infoHashes)
+}
+
+// torrentTrackers returns every distinct tracker of a torrent, tier by tier.
+func torrentTrackers(torrent *torrentdata.TorrentData) []string {
+	seen := make(map[string]bool)
+	var trackers []string
+	for _, tier := range torrent.AnnounceList {
+		for _, trackerURL := range tier {
+			if !seen[trackerURL] {
+				seen[trackerURL] = true
+				trackers = append(trackers, trackerURL)
+			}
+		}
+	}
+	return trackers
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/mattheworford/gotorrent/internal/trackerserver"
+)
+
+func runTracker(args []string) error {
+	fs := flag.NewFlagSet("tracker", flag.ContinueOnError)
+	httpAddr := fs.String("http", ":6969", "address to serve the HTTP tracker on")
+	udpAddr := fs.String("udp", ":6969", "address to serve the UDP tracker on (empty to disable)")
+	interval := fs.Duration("interval", 30*time.Minute, "announce interval sent to clients")
+	minInterval := fs.Duration("min-interval", 0, "minimum announce interval sent to clients")
+	peerTTL := fs.Duration("peer-ttl", 0, "time after which silent peers are dropped (default twice the interval)")
+	var allow stringList
+	fs.Var(&allow, "allow", "only serve this torrent, given as a hex info hash or a .torrent file (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: gotorrent tracker [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errors.New("unexpected arguments")
+	}
+
+	allowlist, err := parseAllowlist(allow)
+	if err != nil {
+		return err
+	}
+	ttl := *peerTTL
+	if ttl == 0 {
+		ttl = 2 * *interval
+	}
+	store := trackerserver.NewStore(ttl)
+	server := trackerserver.NewHTTPServer(store)
+	server.Allowlist = allowlist
+	server.Interval = *interval
+	server.MinInterval = *minInterval
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go sweepStore(ctx, store, time.Minute)
+
+	errs := make(chan error, 2)
+	if *udpAddr != "" {
+		udpServer, err := trackerserver.NewUDPServer(store, 0, 0)
+		if err != nil {
+			return err
+		}
+		udpServer.Allowlist = allowlist
+		udpServer.Interval = *interval
+		conn, err := net.ListenPacket("udp", *udpAddr)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		go func() { errs <- udpServer.Serve(conn) }()
+		fmt.Fprintf(os.Stderr, "gotorrent tracker: serving UDP on %s\n", conn.LocalAddr())
+	}
+
+	httpServer := &http.Server{Addr: *httpAddr, Handler: server, ReadHeaderTimeout: 10 * time.Second}
+	go func() { errs <- httpServer.ListenAndServe() }()
+	fmt.Fprintf(os.Stderr, "gotorrent tracker: serving HTTP on %s\n", *httpAddr)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return httpServer.Shutdown(shutdownCtx)
+}
+
+// parseAllowlist reads info hashes given in hex or as torrent files. No entries allow
+// every torrent.
+func parseAllowlist(entries []string) (trackerserver.Allowlist, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	allowlist := make(trackerserver.Allowlist)
+	for _, entry := range entries {
+		if decoded, err := hex.DecodeString(entry); err == nil && len(decoded) == 20 {
+			allowlist[[20]byte(decoded)] = struct{}{}
+			continue
+		}
+		torrent, err := loadTorrent(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -allow %q: %w", entry, err)
+		}
+		for _, infoHash := range torrent.SwarmHashes() {
+			allowlist[infoHash] = struct{}{}
+		}
+	}
+	return allowlist, nil
+}
+
+// sweepStore drops expired peers from the store until ctx is cancelled.
+func sweepStore(ctx context.Context, store *trackerserver.Store, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			store.Sweep()
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mattheworford/gotorrent/internal/torrentdata"
+)
+
+// validationResult represents the validation report of a single torrent file.
+type validationResult struct {
+	Path string `json:"path"`
+	*torrentdata.Report
+}
+
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the reports as JSON")
+	strict := fs.Bool("strict", false, "treat warnings as errors")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: gotorrent validate [flags] <file.torrent>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("expected at least one torrent file")
+	}
+
+	results := make([]validationResult, 0, fs.NArg())
+	failed := 0
+	for _, path := range fs.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		report := torrentdata.Validate(data)
+		if !report.Valid() || (*strict && len(report.Issues) > 0) {
+			failed++
+		}
+		results = append(results, validationResult{Path: path, Report: report})
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, result := range results {
+			if len(result.Issues) == 0 {
+				fmt.Printf("%s: ok\n", result.Path)
+			}
+			for _, issue := range result.Issues {
+				fmt.Printf("%s: %s\n", result.Path, issue)
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d torrents failed validation", failed, len(results))
+	}
+	return nil
+}